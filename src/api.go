@@ -11,9 +11,9 @@ import (
 	"net"
 	"net/http"
 	"path/filepath"
+	"slices"
 	"sync"
 	"syscall"
-	"time"
 
 	"github.com/dgraph-io/badger/v4"
 	"github.com/gin-gonic/gin"
@@ -21,6 +21,7 @@ import (
 	"github.com/ocyss/sub-store-lab/src/env"
 	"github.com/ocyss/sub-store-lab/src/models"
 	"github.com/ocyss/sub-store-lab/src/tester"
+	"github.com/ocyss/sub-store-lab/src/tester/purity"
 	"github.com/ocyss/sub-store-lab/src/utils"
 	"github.com/samber/lo"
 	"github.com/sourcegraph/conc/pool"
@@ -49,6 +50,7 @@ func ScriptHandler(c *gin.Context) {
 	db := env.GetDB()
 
 	subs := make(map[string]*beautify.Subscription)
+	proxieTTL := tester.ProxieTTL(&args.Conf)
 
 	for _, proxie := range args.Proxies {
 		if proxie["servername"] == nil && proxie["sni"] != "" {
@@ -62,12 +64,22 @@ func ScriptHandler(c *gin.Context) {
 				SubNameNum: subNum,
 				Nodes:      make([]*beautify.ProxieNode, 0),
 			}
+			if src, ok := args.SubSources[utils.GetD(proxie, "_subName", "")]; ok && src.SubUserinfo != "" {
+				subs[subName].Userinfo = models.ParseSubscriptionUserinfo(src.SubUserinfo)
+			}
 		}
 
 		node := subs[subName].AddNode(proxie)
 
 		p.Go(func() error {
+			proxyInfo := args.GetProxieInfo(proxie)
 			delay, err := utils.RunMihomoDelayTest(proxie)
+			// 失败同样计入延迟历史，用于计算稳定性
+			if history, err := tester.RecordDelay(proxyInfo, lo.Ternary(err == nil, delay, 0)); err != nil {
+				slog.Error("tester.RecordDelay", "proxie", proxyInfo.Id, "error", err)
+			} else {
+				node.SetStability(history.Stability())
+			}
 			if err != nil {
 				var dnsErr *net.DNSError
 				if errors.As(err, &dnsErr) || errors.Is(err, context.DeadlineExceeded) {
@@ -89,9 +101,11 @@ func ScriptHandler(c *gin.Context) {
 			if err != nil {
 				return fmt.Errorf("%s json.Marshal: %w", node.Name, err)
 			}
-			proxyInfo := args.GetProxieInfo(proxie)
 			err = db.Update(func(txn *badger.Txn) error {
-				return txn.SetEntry(badger.NewEntry(proxyInfo.Id.ToKey(), data).WithTTL(time.Hour * 48))
+				if err := txn.SetEntry(badger.NewEntry(proxyInfo.Id.ToKey(), data).WithTTL(proxieTTL)); err != nil {
+					return err
+				}
+				return tester.RecordAlias(txn, proxyInfo.Id, proxyInfo.Name)
 			})
 			if err != nil {
 				return fmt.Errorf("%s db.Update: %w", node.Name, err)
@@ -104,6 +118,12 @@ func ScriptHandler(c *gin.Context) {
 		slog.Error("utils.CreateMihomoDelay", "error", err)
 	}
 
+	// 读取结果前同步conf，conf变化导致结果被清理的测试类型整体重新运行
+	invalidated := cron.SyncConf(&args.Conf)
+	jobs := lo.MapValues(tester.GetTesters(), func(t models.ProxieTester, _ models.ProxieTesterType) *tester.CronJob {
+		return cron.GetJob(tester.GetCronJob(&args.Conf, t))
+	})
+
 	p = pool.New().WithMaxGoroutines(50).WithErrors()
 	var filterProxieMu sync.Mutex
 	filterProxie := lo.MapValues(tester.GetTesters(), func(t models.ProxieTester, _ models.ProxieTesterType) map[models.ProxieKey]struct{} {
@@ -115,13 +135,21 @@ func ScriptHandler(c *gin.Context) {
 			proxyInfo := args.GetProxieInfo(node.Proxie)
 			for name, t := range tester.GetTesters() {
 				p.Go(func() error {
-					cron.GetJob(tester.GetCronJob(&args.Conf, t))
 					result, err := t.GetResult(proxyInfo)
 					if err != nil {
 						return fmt.Errorf("tester[%s].GetResult id: %s: %w", name, proxyInfo.Id, err)
 					}
+					quarantined := false
+					if failure, err := tester.GetFailure(name, proxyInfo); err == nil && failure != nil && failure.Quarantined {
+						node.SetQuarantined()
+						quarantined = true
+					}
 					switch result := result.(type) {
 					case nil:
+						// 隔离中的节点没有结果，由定时任务按隔离间隔重试，不随每次请求重新测试
+						if quarantined {
+							break
+						}
 						filterProxieMu.Lock()
 						filterProxie[name][proxyInfo.Id] = struct{}{}
 						filterProxieMu.Unlock()
@@ -141,11 +169,9 @@ func ScriptHandler(c *gin.Context) {
 	}
 
 	for testerType, proxies := range filterProxie {
-		// 更新conf/cron
-		t := tester.GetTester(testerType)
-		job := cron.GetJob(tester.GetCronJob(&args.Conf, t))
-		if args.Platform == "JSON" {
-			// JSON平台强制后台刷新
+		job := jobs[testerType]
+		if args.Platform == "JSON" || slices.Contains(invalidated, testerType) {
+			// JSON平台强制后台刷新，结果被清理时重新测试conf下全部节点
 			go func() {
 				if err := job.Run(); err != nil {
 					slog.Error("testerFlag async job.Run", "error", err)
@@ -163,16 +189,66 @@ func ScriptHandler(c *gin.Context) {
 		}
 	}
 
-	res := beautify.ProcessNodes(&args.Conf, subs)
+	res, groups := beautify.ProcessNodes(&args.Conf, subs)
 	if env.Conf.OutputNodesJson || env.Conf.Debug {
 		err := utils.JsonToFile(subs, filepath.Join(env.Conf.DataDir, "sub-store-lab.json"))
 		if err != nil {
 			slog.Error("utils.JsonToFile", "error", err)
 		}
 	}
+	if args.Conf.ProxyGroups {
+		c.JSON(http.StatusOK, gin.H{
+			"proxies":      res,
+			"proxy-groups": lo.CoalesceSliceOrEmpty(groups, []map[string]any{}),
+		})
+		return
+	}
 	c.JSON(http.StatusOK, res)
 }
 
+// ExitsHandler 按出口IP汇总节点，可选参数 conf 指定conf id，shared=true 仅返回多个节点共享的出口
+func ExitsHandler(c *gin.Context) {
+	prefix := []byte(models.ProxieResultKeyPrefix)
+	if confId := c.Query("conf"); confId != "" {
+		prefix = (&models.CronJobKey{ConfId: confId}).ToProxieResultPrefixKey()
+	}
+	exits, err := purity.GetExits(prefix, (&tester.Purity{}).Name())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if c.Query("shared") == "true" {
+		exits = lo.Filter(exits, func(exit *purity.Exit, _ int) bool {
+			return exit.Shared
+		})
+	}
+	c.JSON(http.StatusOK, exits)
+}
+
+// QuotaHandler 返回各检测器API密钥的用量及冷却状态
+func QuotaHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, purity.GetQuotaStats())
+}
+
+// ReportHandler 返回最近一次脚本请求的节点处理统计，可选参数 conf 指定conf id
+func ReportHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, beautify.GetReports(c.Query("conf")))
+}
+
+// SubscriptionsHandler 返回各订阅最近一次提取的剩余流量及到期时间，可选参数 conf 指定conf id
+func SubscriptionsHandler(c *gin.Context) {
+	states, err := beautify.GetSubscriptionStates(c.Query("conf"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, states)
+}
+
 func parseBody(c *gin.Context) (*models.Args, error) {
 	var args models.Args
 	err := c.ShouldBindBodyWithJSON(&args)
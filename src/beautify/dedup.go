@@ -0,0 +1,125 @@
+package beautify
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ocyss/sub-store-lab/src/models"
+	"github.com/samber/lo"
+)
+
+const (
+	DropDuplicateFingerprint = "duplicate_fingerprint"
+	DropDuplicateExitIP      = "duplicate_exit_ip"
+)
+
+// SharedInfra 多个订阅共享的服务器(连接指纹相同)或出口IP
+type SharedInfra struct {
+	By            string   // fingerprint/exit_ip
+	Value         string   // 连接指纹或出口IP
+	Subscriptions []string // 共享的订阅，按订阅顺序
+	Kept          string   // 保留副本的订阅
+}
+
+// deduper 跨订阅重复节点去重，同一订阅内的重复节点不处理
+type deduper struct {
+	by       []string
+	keep     string
+	strategy SortStrategy
+	subOrder map[string]int // 订阅名 -> 订阅顺序
+}
+
+func newDeduper(conf *models.DedupConf, strategy SortStrategy) (*deduper, error) {
+	for _, by := range conf.By {
+		if by != models.DedupByFingerprint && by != models.DedupByExitIP {
+			return nil, fmt.Errorf("未知的去重依据: %s", by)
+		}
+	}
+	switch conf.Keep {
+	case "", models.DedupKeepBest, models.DedupKeepPriority, models.DedupKeepCost:
+	default:
+		return nil, fmt.Errorf("未知的去重保留规则: %s", conf.Keep)
+	}
+	return &deduper{
+		by:       lo.Uniq(conf.By),
+		keep:     lo.CoalesceOrEmpty(conf.Keep, models.DedupKeepBest),
+		strategy: strategy,
+	}, nil
+}
+
+// dedupKey 节点按依据的去重键，为空时不参与去重
+func dedupKey(by string, node *ProxieNode) string {
+	switch by {
+	case models.DedupByFingerprint:
+		return models.ProxieFingerprint(node.Proxie)
+	case models.DedupByExitIP:
+		return lo.FromPtr(node.Purity.IP)
+	}
+	return ""
+}
+
+// compare 按保留规则比较两个副本，越小越优先保留
+func (d *deduper) compare(a, b *ProxieNode) int {
+	var c int
+	switch d.keep {
+	case models.DedupKeepPriority:
+		c = cmp.Compare(d.subOrder[a.Subscription.SubName], d.subOrder[b.Subscription.SubName])
+	case models.DedupKeepCost:
+		c = cmp.Compare(rateValue(a.Name), rateValue(b.Name))
+	}
+	if c != 0 {
+		return c
+	}
+	if c := cmp.Compare(d.strategy.Score(a), d.strategy.Score(b)); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(a.Delay, b.Delay); c != 0 {
+		return c
+	}
+	return cmp.Compare(d.subOrder[a.Subscription.SubName], d.subOrder[b.Subscription.SubName])
+}
+
+// dedup 按依据依次查找多个订阅中的重复节点，保留最优副本所在订阅的节点，丢弃其他订阅的副本
+// 保留的节点记录共享的其他订阅，返回剩余节点(保持原顺序)及共享信息
+func (d *deduper) dedup(nodes []*ProxieNode, drop func(node *ProxieNode, reason string)) ([]*ProxieNode, []SharedInfra) {
+	var shared []SharedInfra
+	for _, by := range d.by {
+		dropped := make(map[*ProxieNode]bool)
+		clusters := lo.GroupBy(lo.Filter(nodes, func(node *ProxieNode, _ int) bool {
+			return dedupKey(by, node) != ""
+		}), func(node *ProxieNode) string {
+			return dedupKey(by, node)
+		})
+		for value, cluster := range clusters {
+			subs := lo.Uniq(lo.Map(cluster, func(node *ProxieNode, _ int) string {
+				return node.Subscription.SubName
+			}))
+			if len(subs) < 2 {
+				continue
+			}
+			slices.SortFunc(subs, func(a, b string) int {
+				return cmp.Or(cmp.Compare(d.subOrder[a], d.subOrder[b]), strings.Compare(a, b))
+			})
+			kept := slices.MinFunc(cluster, d.compare).Subscription.SubName
+			others := lo.Without(subs, kept)
+			for _, node := range cluster {
+				if node.Subscription.SubName != kept {
+					dropped[node] = true
+					drop(node, lo.Ternary(by == models.DedupByFingerprint, DropDuplicateFingerprint, DropDuplicateExitIP))
+					continue
+				}
+				node.SharedWith = lo.Uniq(append(node.SharedWith, others...))
+			}
+			shared = append(shared, SharedInfra{By: by, Value: value, Subscriptions: subs, Kept: kept})
+		}
+		nodes = lo.Filter(nodes, func(node *ProxieNode, _ int) bool {
+			return !dropped[node]
+		})
+	}
+	slices.SortFunc(shared, func(a, b SharedInfra) int {
+		return cmp.Or(strings.Compare(a.By, b.By), strings.Compare(a.Value, b.Value))
+	})
+	return nodes, shared
+}
@@ -0,0 +1,95 @@
+package beautify
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/ocyss/sub-store-lab/src/models"
+	"github.com/samber/lo"
+)
+
+func Test_deduper_dedup(t *testing.T) {
+	subA, subB, subC := &Subscription{SubName: "A"}, &Subscription{SubName: "B"}, &Subscription{SubName: "C"}
+	node := func(name string, sub *Subscription, server string, delay uint16, ip string) *ProxieNode {
+		n := testNode(name, delay, 0, nil, 0)
+		n.Subscription = sub
+		n.Proxie = map[string]any{"name": name, "type": "ss", "server": server, "port": 443, "password": "pw"}
+		n.Purity.IP = lo.ToPtr(ip)
+		return n
+	}
+	newNodes := func() []*ProxieNode {
+		return []*ProxieNode{
+			node("a1", subA, "s1.example.com", 300, "1.1.1.1"),
+			node("a2", subA, "s2.example.com", 100, "3.3.3.3"),
+			node("b1 [0.5x]", subB, "s1.example.com", 200, "1.1.1.1"),
+			node("c1", subC, "s3.example.com", 250, "1.1.1.1"),
+			node("c2", subC, "s4.example.com", 150, "2.2.2.2"),
+			node("c3", subC, "s5.example.com", 120, "2.2.2.2"),
+		}
+	}
+
+	tests := []struct {
+		name       string
+		conf       models.DedupConf
+		want       []string
+		wantShared map[string][]string // 保留节点 -> 共享的其他订阅
+		wantDrops  map[string]int
+	}{
+		{
+			name:       "fingerprint best",
+			conf:       models.DedupConf{By: []string{models.DedupByFingerprint}},
+			want:       []string{"a2", "b1 [0.5x]", "c1", "c2", "c3"},
+			wantShared: map[string][]string{"b1 [0.5x]": {"A"}},
+			wantDrops:  map[string]int{DropDuplicateFingerprint: 1},
+		},
+		{
+			name:       "fingerprint and exit ip",
+			conf:       models.DedupConf{By: []string{models.DedupByFingerprint, models.DedupByExitIP}},
+			want:       []string{"a2", "b1 [0.5x]", "c2", "c3"},
+			wantShared: map[string][]string{"b1 [0.5x]": {"A", "C"}},
+			wantDrops:  map[string]int{DropDuplicateFingerprint: 1, DropDuplicateExitIP: 1},
+		},
+		{
+			name:       "priority",
+			conf:       models.DedupConf{By: []string{models.DedupByFingerprint, models.DedupByExitIP}, Keep: models.DedupKeepPriority},
+			want:       []string{"a1", "a2", "c2", "c3"},
+			wantShared: map[string][]string{"a1": {"B", "C"}},
+			wantDrops:  map[string]int{DropDuplicateFingerprint: 1, DropDuplicateExitIP: 1},
+		},
+		{
+			name:       "cost",
+			conf:       models.DedupConf{By: []string{models.DedupByExitIP}, Keep: models.DedupKeepCost},
+			want:       []string{"a2", "b1 [0.5x]", "c2", "c3"},
+			wantShared: map[string][]string{"b1 [0.5x]": {"A", "C"}},
+			wantDrops:  map[string]int{DropDuplicateExitIP: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := newDeduper(&tt.conf, delayStrategy{})
+			if err != nil {
+				t.Fatalf("newDeduper() error = %v", err)
+			}
+			d.subOrder = map[string]int{"A": 0, "B": 1, "C": 2}
+			drops := make(map[string]int)
+			got, _ := d.dedup(newNodes(), func(_ *ProxieNode, reason string) {
+				drops[reason]++
+			})
+			if names := lo.Map(got, func(n *ProxieNode, _ int) string { return n.Name }); !slices.Equal(names, tt.want) {
+				t.Errorf("dedup() = %v, want %v", names, tt.want)
+			}
+			for _, n := range got {
+				if !slices.Equal(n.SharedWith, tt.wantShared[n.Name]) {
+					t.Errorf("%s.SharedWith = %v, want %v", n.Name, n.SharedWith, tt.wantShared[n.Name])
+				}
+			}
+			if !lo.ElementsMatch(lo.Entries(drops), lo.Entries(tt.wantDrops)) {
+				t.Errorf("drops = %v, want %v", drops, tt.wantDrops)
+			}
+		})
+	}
+
+	if _, err := newDeduper(&models.DedupConf{By: []string{"server"}}, delayStrategy{}); err == nil {
+		t.Error("newDeduper() with unknown by should fail")
+	}
+}
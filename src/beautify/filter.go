@@ -0,0 +1,466 @@
+package beautify
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/samber/lo"
+)
+
+// 节点过滤表达式，结果为假的节点被丢弃，示例:
+//   purity.risk < 40 && usage == "Residential" && speed_mbps > 50 && !country in ["CN"]
+// 支持 && || ! 括号，比较 == != < <= > >=，列表包含 in [..]，字符串比较不区分大小写
+// 最外层以 && 连接的每一项作为一个子句，分别统计丢弃的节点数
+// 未知的值(如未检测出风险评分)参与比较时结果为假
+
+// filterFields 表达式可用的字段
+var filterFields = map[string]func(p *ProxieNode) any{
+	"name":            func(p *ProxieNode) any { return p.Name },
+	"sub":             func(p *ProxieNode) any { return p.Subscription.SubName },
+	"delay":           func(p *ProxieNode) any { return float64(p.Delay) },
+	"speed_mbps":      func(p *ProxieNode) any { return float64(p.Speed.SpeedMbps) },
+	"stability":       func(p *ProxieNode) any { return p.Stability },
+	"rate":            func(p *ProxieNode) any { return rateValue(p.Name) },
+	"quarantined":     func(p *ProxieNode) any { return p.Quarantined },
+	"stale":           func(p *ProxieNode) any { return p.Purity.Stale || p.Speed.Stale },
+	"country":         func(p *ProxieNode) any { return optional(p.Purity.Country) },
+	"claimed_country": func(p *ProxieNode) any { return optional(lo.EmptyableToPtr(p.ClaimedCountry)) },
+	"mismatch":        func(p *ProxieNode) any { return p.CountryMismatch },
+	"usage":           func(p *ProxieNode) any { return optional(p.Purity.UsageType) },
+	"city":            func(p *ProxieNode) any { return optional(p.Purity.City) },
+	"region":          func(p *ProxieNode) any { return optional(p.Purity.Region) },
+	"asn":             func(p *ProxieNode) any { return optional(p.Purity.ASN) },
+	"org":             func(p *ProxieNode) any { return optional(p.Purity.Org) },
+	"ip":              func(p *ProxieNode) any { return optional(p.Purity.IP) },
+	"purity.risk":     func(p *ProxieNode) any { return optional(p.Purity.RiskScore) },
+	"purity.proxy":    func(p *ProxieNode) any { return optional(p.Purity.RiskFactors.IsProxy) },
+	"purity.vpn":      func(p *ProxieNode) any { return optional(p.Purity.RiskFactors.IsVPN) },
+	"purity.tor":      func(p *ProxieNode) any { return optional(p.Purity.RiskFactors.IsTor) },
+	"purity.server":   func(p *ProxieNode) any { return optional(p.Purity.RiskFactors.IsServer) },
+	"purity.abuse":    func(p *ProxieNode) any { return optional(p.Purity.RiskFactors.IsAbuse) },
+	"purity.bot":      func(p *ProxieNode) any { return optional(p.Purity.RiskFactors.IsBot) },
+}
+
+// optional 将可选字段转换为表达式的值，nil表示未知
+func optional[T ~string | ~int | ~bool](v *T) any {
+	if v == nil {
+		return nil
+	}
+	switch v := any(*v).(type) {
+	case int:
+		return float64(v)
+	case bool:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Filter 已解析的过滤表达式
+type Filter struct {
+	Clauses []FilterClause
+}
+
+// FilterClause 最外层 && 连接的子句
+type FilterClause struct {
+	Text string
+	expr filterExpr
+}
+
+// ParseFilter 解析过滤表达式，为空时返回nil
+func ParseFilter(text string) (*Filter, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+	tokens, err := lexFilter(text)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("位置%d: 多余的 %q", tok.pos, tok.text)
+	}
+
+	filter := &Filter{}
+	if and, ok := expr.(*andExpr); ok {
+		for i, operand := range and.operands {
+			span := and.spans[i]
+			filter.Clauses = append(filter.Clauses, FilterClause{Text: strings.TrimSpace(text[span[0]:span[1]]), expr: operand})
+		}
+	} else {
+		filter.Clauses = []FilterClause{{Text: strings.TrimSpace(text), expr: expr}}
+	}
+	return filter, nil
+}
+
+// Match 返回节点是否保留，丢弃时返回第一个结果为假的子句序号
+func (f *Filter) Match(node *ProxieNode) (bool, int) {
+	for i, clause := range f.Clauses {
+		if !truthy(clause.expr.eval(node)) {
+			return false, i
+		}
+	}
+	return true, -1
+}
+
+type filterExpr interface {
+	eval(node *ProxieNode) any
+}
+
+type (
+	andExpr struct {
+		operands []filterExpr
+		spans    [][2]int // 各项在原表达式中的位置
+	}
+	orExpr  struct{ operands []filterExpr }
+	notExpr struct{ operand filterExpr }
+	cmpExpr struct {
+		op          string
+		left, right filterExpr
+	}
+	inExpr struct {
+		left filterExpr
+		list []filterExpr
+	}
+	fieldExpr   struct{ get func(p *ProxieNode) any }
+	literalExpr struct{ value any }
+)
+
+func (e *andExpr) eval(node *ProxieNode) any {
+	for _, operand := range e.operands {
+		if !truthy(operand.eval(node)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *orExpr) eval(node *ProxieNode) any {
+	for _, operand := range e.operands {
+		if truthy(operand.eval(node)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *notExpr) eval(node *ProxieNode) any {
+	return !truthy(e.operand.eval(node))
+}
+
+func (e *cmpExpr) eval(node *ProxieNode) any {
+	return compare(e.op, e.left.eval(node), e.right.eval(node))
+}
+
+func (e *inExpr) eval(node *ProxieNode) any {
+	left := e.left.eval(node)
+	for _, item := range e.list {
+		if compare("==", left, item.eval(node)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *fieldExpr) eval(node *ProxieNode) any {
+	return e.get(node)
+}
+
+func (e *literalExpr) eval(_ *ProxieNode) any {
+	return e.value
+}
+
+func truthy(v any) bool {
+	b, ok := v.(bool)
+	return ok && b
+}
+
+// compare 比较两个值，类型不一致或未知时为假
+func compare(op string, a, b any) bool {
+	switch a := a.(type) {
+	case float64:
+		b, ok := b.(float64)
+		if !ok {
+			return false
+		}
+		switch op {
+		case "==":
+			return a == b
+		case "!=":
+			return a != b
+		case "<":
+			return a < b
+		case "<=":
+			return a <= b
+		case ">":
+			return a > b
+		case ">=":
+			return a >= b
+		}
+	case string:
+		b, ok := b.(string)
+		if !ok {
+			return false
+		}
+		c := strings.Compare(strings.ToLower(a), strings.ToLower(b))
+		switch op {
+		case "==":
+			return c == 0
+		case "!=":
+			return c != 0
+		case "<":
+			return c < 0
+		case "<=":
+			return c <= 0
+		case ">":
+			return c > 0
+		case ">=":
+			return c >= 0
+		}
+	case bool:
+		b, ok := b.(bool)
+		if !ok {
+			return false
+		}
+		switch op {
+		case "==":
+			return a == b
+		case "!=":
+			return a != b
+		}
+	}
+	return false
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokOp
+)
+
+type filterToken struct {
+	kind tokenKind
+	text string
+	pos  int // 起始字节位置
+	end  int
+}
+
+func lexFilter(text string) ([]filterToken, error) {
+	var tokens []filterToken
+	for i := 0; i < len(text); {
+		c := rune(text[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(text[i+1:], text[i])
+			if end < 0 {
+				return nil, fmt.Errorf("位置%d: 字符串未结束", i)
+			}
+			tokens = append(tokens, filterToken{kind: tokString, text: text[i+1 : i+1+end], pos: i, end: i + end + 2})
+			i += end + 2
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(text) && text[i+1] >= '0' && text[i+1] <= '9':
+			j := i
+			for j < len(text) && (text[j] >= '0' && text[j] <= '9' || text[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, filterToken{kind: tokNumber, text: text[i:j], pos: i, end: j})
+			i = j
+		case c == '_' || c < unicode.MaxASCII && unicode.IsLetter(c):
+			j := i
+			for j < len(text) && (text[j] == '_' || text[j] == '.' || text[j] < unicode.MaxASCII && (unicode.IsLetter(rune(text[j])) || unicode.IsDigit(rune(text[j])))) {
+				j++
+			}
+			tokens = append(tokens, filterToken{kind: tokIdent, text: text[i:j], pos: i, end: j})
+			i = j
+		default:
+			op := ""
+			for _, candidate := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")", "[", "]", ","} {
+				if strings.HasPrefix(text[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("位置%d: 无法识别的字符 %q", i, c)
+			}
+			tokens = append(tokens, filterToken{kind: tokOp, text: op, pos: i, end: i + len(op)})
+			i += len(op)
+		}
+	}
+	return append(tokens, filterToken{kind: tokEOF, pos: len(text), end: len(text)}), nil
+}
+
+type filterParser struct {
+	tokens []filterToken
+	i      int
+}
+
+func (p *filterParser) peek() filterToken {
+	return p.tokens[p.i]
+}
+
+func (p *filterParser) next() filterToken {
+	tok := p.tokens[p.i]
+	if tok.kind != tokEOF {
+		p.i++
+	}
+	return tok
+}
+
+func (p *filterParser) isOp(op string) bool {
+	tok := p.peek()
+	return tok.kind == tokOp && tok.text == op
+}
+
+func (p *filterParser) expect(op string) error {
+	if tok := p.next(); tok.kind != tokOp || tok.text != op {
+		return fmt.Errorf("位置%d: 需要 %q", tok.pos, op)
+	}
+	return nil
+}
+
+// parseOr: and ('||' and)*
+func (p *filterParser) parseOr() (filterExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	operands := []filterExpr{left}
+	for p.isOp("||") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, right)
+	}
+	if len(operands) == 1 {
+		return left, nil
+	}
+	return &orExpr{operands: operands}, nil
+}
+
+// parseAnd: unary ('&&' unary)*
+func (p *filterParser) parseAnd() (filterExpr, error) {
+	and := &andExpr{}
+	for {
+		start := p.peek().pos
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		and.operands = append(and.operands, operand)
+		and.spans = append(and.spans, [2]int{start, p.tokens[p.i-1].end})
+		if !p.isOp("&&") {
+			break
+		}
+		p.next()
+	}
+	if len(and.operands) == 1 {
+		return and.operands[0], nil
+	}
+	return and, nil
+}
+
+// parseUnary: '!' unary | comparison，! 作用于整个比较，如 !country in ["CN"]
+func (p *filterParser) parseUnary() (filterExpr, error) {
+	if p.isOp("!") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notExpr{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+// parseComparison: operand (op operand | 'in' list)?
+func (p *filterParser) parseComparison() (filterExpr, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	tok := p.peek()
+	switch {
+	case tok.kind == tokOp && lo.Contains([]string{"==", "!=", "<", "<=", ">", ">="}, tok.text):
+		p.next()
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return &cmpExpr{op: tok.text, left: left, right: right}, nil
+	case tok.kind == tokIdent && tok.text == "in":
+		p.next()
+		if err := p.expect("["); err != nil {
+			return nil, err
+		}
+		in := &inExpr{left: left}
+		for !p.isOp("]") {
+			item, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			in.list = append(in.list, item)
+			if !p.isOp(",") {
+				break
+			}
+			p.next()
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return in, nil
+	}
+	return left, nil
+}
+
+// parseOperand: field | number | string | true | false | '(' or ')'
+func (p *filterParser) parseOperand() (filterExpr, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber:
+		v, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("位置%d: 无效的数字 %s", tok.pos, tok.text)
+		}
+		return &literalExpr{value: v}, nil
+	case tokString:
+		return &literalExpr{value: tok.text}, nil
+	case tokIdent:
+		switch tok.text {
+		case "true", "false":
+			return &literalExpr{value: tok.text == "true"}, nil
+		}
+		get, ok := filterFields[strings.ToLower(tok.text)]
+		if !ok {
+			return nil, fmt.Errorf("位置%d: 未知字段 %s", tok.pos, tok.text)
+		}
+		return &fieldExpr{get: get}, nil
+	case tokOp:
+		if tok.text == "(" {
+			expr, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return expr, nil
+		}
+	case tokEOF:
+		return nil, fmt.Errorf("位置%d: 表达式不完整", tok.pos)
+	}
+	return nil, fmt.Errorf("位置%d: 意外的 %q", tok.pos, tok.text)
+}
@@ -0,0 +1,86 @@
+package beautify
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/ocyss/sub-store-lab/src/tester/purity"
+	"github.com/samber/lo"
+)
+
+func TestFilter_Match(t *testing.T) {
+	const expr = `purity.risk < 40 && usage == "Residential" && speed_mbps > 50 && !country in ["CN", 'RU']`
+
+	filter, err := ParseFilter(expr)
+	if err != nil {
+		t.Fatalf("ParseFilter() error = %v", err)
+	}
+	wantClauses := []string{`purity.risk < 40`, `usage == "Residential"`, `speed_mbps > 50`, `!country in ["CN", 'RU']`}
+	if got := lo.Map(filter.Clauses, func(c FilterClause, _ int) string { return c.Text }); !slices.Equal(got, wantClauses) {
+		t.Fatalf("Clauses = %q, want %q", got, wantClauses)
+	}
+
+	node := func(risk *int, usage purity.UsageType, mbps int, country string) *ProxieNode {
+		n := testNode("n", 100, mbps, risk, 0)
+		n.Purity.UsageType = lo.ToPtr(usage)
+		n.Purity.Country = lo.ToPtr(country)
+		return n
+	}
+	tests := []struct {
+		name       string
+		node       *ProxieNode
+		wantOk     bool
+		wantClause int
+	}{
+		{"kept", node(lo.ToPtr(10), purity.UsageTypeResidential, 100, "HK"), true, -1},
+		{"unknown risk", node(nil, purity.UsageTypeResidential, 100, "HK"), false, 0},
+		{"usage case insensitive", node(lo.ToPtr(10), "residential", 100, "JP"), true, -1},
+		{"datacenter", node(lo.ToPtr(10), purity.UsageTypeDatacenter, 100, "HK"), false, 1},
+		{"slow", node(lo.ToPtr(10), purity.UsageTypeResidential, 50, "HK"), false, 2},
+		{"excluded country", node(lo.ToPtr(10), purity.UsageTypeResidential, 100, "cn"), false, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, clause := filter.Match(tt.node)
+			if ok != tt.wantOk || clause != tt.wantClause {
+				t.Errorf("Match() = %v, %d, want %v, %d", ok, clause, tt.wantOk, tt.wantClause)
+			}
+		})
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		expr        string
+		wantClauses int
+		wantErr     bool
+	}{
+		{expr: "", wantClauses: 0},
+		{expr: `delay < 300 || (speed_mbps >= 100 && !quarantined)`, wantClauses: 1},
+		{expr: `(delay < 300 || stability > 0.8) && mismatch == false`, wantClauses: 2},
+		{expr: `latency < 300`, wantErr: true},
+		{expr: `delay <`, wantErr: true},
+		{expr: `country in ["CN"`, wantErr: true},
+		{expr: `name == "unterminated`, wantErr: true},
+		{expr: `delay < 300 )`, wantErr: true},
+		{expr: `delay # 1`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			filter, err := ParseFilter(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got := 0
+			if filter != nil {
+				got = len(filter.Clauses)
+			}
+			if got != tt.wantClauses {
+				t.Errorf("ParseFilter() clauses = %d, want %d", got, tt.wantClauses)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package beautify
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"text/template"
+
+	"github.com/ocyss/sub-store-lab/src/env"
+	"github.com/ocyss/sub-store-lab/src/static"
+	"github.com/ocyss/sub-store-lab/src/tester/purity"
+	"github.com/samber/lo"
+	"gopkg.in/yaml.v3"
+)
+
+// groupTemplate 策略组模板，Name为模板，其余字段原样输出
+type groupTemplate struct {
+	Name    string         `yaml:"name"`
+	Options map[string]any `yaml:",inline"`
+}
+
+// LabGroups override.yaml 中 lab-groups 的配置
+type LabGroups struct {
+	Country     []groupTemplate `yaml:"country"`     // 每个国家生成的分组
+	Residential *groupTemplate  `yaml:"residential"` // 全部家宽节点
+	LowRisk     *groupTemplate  `yaml:"low-risk"`    // 风险评分不高于MaxRisk的节点
+	MaxRisk     int             `yaml:"max-risk"`
+}
+
+// GroupFacts 国家分组名称模板可用的字段
+type GroupFacts struct {
+	Flag    string
+	Country string
+	Count   int
+}
+
+// loadLabGroups 读取 override.yaml 的 lab-groups，未配置时使用内置默认值
+func loadLabGroups() (*LabGroups, error) {
+	raw, ok := env.OverrideYaml["lab-groups"]
+	if !ok {
+		var defaults struct {
+			LabGroups LabGroups `yaml:"lab-groups"`
+		}
+		if err := yaml.Unmarshal(static.OverrideYamlByte, &defaults); err != nil {
+			return nil, fmt.Errorf("解析内置lab-groups失败: %w", err)
+		}
+		return &defaults.LabGroups, nil
+	}
+	data, err := yaml.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("解析lab-groups失败: %w", err)
+	}
+	groups := &LabGroups{}
+	if err := yaml.Unmarshal(data, groups); err != nil {
+		return nil, fmt.Errorf("解析lab-groups失败: %w", err)
+	}
+	return groups, nil
+}
+
+// build 按国家组顺序生成策略组，names为各国家组内格式化后的节点名称，不含节点的分组不生成
+func (l *LabGroups) build(countries []string, names map[string][]string, nodes []*ProxieNode) ([]map[string]any, error) {
+	var groups []map[string]any
+	for _, tmpl := range l.Country {
+		t, err := template.New("group").Parse(tmpl.Name)
+		if err != nil {
+			return nil, fmt.Errorf("解析分组名称模板失败: %w", err)
+		}
+		for _, country := range countries {
+			if len(names[country]) == 0 {
+				continue
+			}
+			var buf bytes.Buffer
+			err := t.Execute(&buf, GroupFacts{
+				Flag:    purity.GetCountryFlag(&country),
+				Country: country,
+				Count:   len(names[country]),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("执行分组名称模板失败: %w", err)
+			}
+			groups = append(groups, tmpl.group(buf.String(), names[country]))
+		}
+	}
+
+	if l.Residential != nil {
+		groups = append(groups, l.Residential.group(l.Residential.Name, nodeNames(nodes, func(node *ProxieNode) bool {
+			return lo.FromPtr(node.Purity.UsageType) == purity.UsageTypeResidential
+		})))
+	}
+	if l.LowRisk != nil {
+		groups = append(groups, l.LowRisk.group(l.LowRisk.Name, nodeNames(nodes, func(node *ProxieNode) bool {
+			return node.Purity.RiskScore != nil && *node.Purity.RiskScore <= l.MaxRisk
+		})))
+	}
+	return lo.Filter(groups, func(group map[string]any, _ int) bool {
+		return len(group["proxies"].([]string)) > 0
+	}), nil
+}
+
+func (g *groupTemplate) group(name string, proxies []string) map[string]any {
+	group := maps.Clone(g.Options)
+	if group == nil {
+		group = make(map[string]any)
+	}
+	group["name"] = name
+	group["proxies"] = proxies
+	return group
+}
+
+// nodeNames 返回满足条件的节点格式化后的名称
+func nodeNames(nodes []*ProxieNode, match func(node *ProxieNode) bool) []string {
+	return lo.FilterMap(nodes, func(node *ProxieNode, _ int) (string, bool) {
+		name, ok := node.Proxie["name"].(string)
+		return name, ok && match(node)
+	})
+}
@@ -0,0 +1,59 @@
+package beautify
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/ocyss/sub-store-lab/src/tester/purity"
+	"github.com/samber/lo"
+)
+
+func TestLabGroups_build(t *testing.T) {
+	labGroups, err := loadLabGroups()
+	if err != nil {
+		t.Fatalf("loadLabGroups() error = %v", err)
+	}
+	node := func(name string, usage purity.UsageType, risk *int) *ProxieNode {
+		n := testNode(name, 100, 0, risk, 0)
+		n.Proxie = map[string]any{"name": name}
+		n.Purity.UsageType = lo.ToPtr(usage)
+		return n
+	}
+	hk1 := node("🇭🇰HK_1", purity.UsageTypeDatacenter, lo.ToPtr(60))
+	hk2 := node("🇭🇰HK_2", purity.UsageTypeResidential, lo.ToPtr(10))
+	jp1 := node("🇯🇵JP_1", purity.UsageTypeResidential, nil)
+
+	groups, err := labGroups.build([]string{"HK", "JP", "US"}, map[string][]string{
+		"HK": {hk1.Name, hk2.Name},
+		"JP": {jp1.Name},
+	}, []*ProxieNode{hk1, hk2, jp1})
+	if err != nil {
+		t.Fatalf("build() error = %v", err)
+	}
+
+	want := []struct {
+		name    string
+		typ     string
+		proxies []string
+	}{
+		{"🇭🇰HK-自动", "url-test", []string{hk1.Name, hk2.Name}},
+		{"🇯🇵JP-自动", "url-test", []string{jp1.Name}},
+		{"🇭🇰HK-故障转移", "fallback", []string{hk1.Name, hk2.Name}},
+		{"🇯🇵JP-故障转移", "fallback", []string{jp1.Name}},
+		{"🏠家宽节点", "url-test", []string{hk2.Name, jp1.Name}},
+		{"🩵低风险节点", "url-test", []string{hk2.Name}},
+	}
+	if len(groups) != len(want) {
+		t.Fatalf("build() returned %d groups, want %d: %v", len(groups), len(want), groups)
+	}
+	for i, w := range want {
+		g := groups[i]
+		if g["name"] != w.name || g["type"] != w.typ || !slices.Equal(g["proxies"].([]string), w.proxies) {
+			t.Errorf("groups[%d] = %v, want %+v", i, g, w)
+		}
+	}
+	// 模板中的其余字段原样输出
+	if groups[0]["interval"] != 300 {
+		t.Errorf("groups[0].interval = %v, want 300", groups[0]["interval"])
+	}
+}
@@ -0,0 +1,121 @@
+package beautify
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/ocyss/sub-store-lab/src/env"
+	"github.com/ocyss/sub-store-lab/src/models"
+)
+
+// nodeIndexTTL 序号映射的有效期
+const nodeIndexTTL = time.Hour * 24 * 30
+
+// nodeIndexReserve 节点消失后保留其序号的时长，期间新节点不会占用该序号，避免客户端选择指向其他服务器
+const nodeIndexReserve = time.Hour * 24 * 7
+
+type indexEntry struct {
+	Index    int
+	LastSeen time.Time
+}
+
+// nodeIndexes conf内各国家的节点身份到序号的映射，见 NodeIdentity
+type nodeIndexes struct {
+	Countries map[string]map[string]*indexEntry
+}
+
+// prune 删除消失超过保留时长的节点序号
+func (n *nodeIndexes) prune(now time.Time) {
+	for country, entries := range n.Countries {
+		for id, entry := range entries {
+			if now.Sub(entry.LastSeen) > nodeIndexReserve {
+				delete(entries, id)
+			}
+		}
+		if len(entries) == 0 {
+			delete(n.Countries, country)
+		}
+	}
+}
+
+// assign 为国家组内的节点分配序号，已有序号的节点沿用，新节点使用未被占用的最小序号
+func (n *nodeIndexes) assign(country string, nodes []*ProxieNode, now time.Time) map[*ProxieNode]int {
+	if n.Countries == nil {
+		n.Countries = make(map[string]map[string]*indexEntry)
+	}
+	entries, ok := n.Countries[country]
+	if !ok {
+		entries = make(map[string]*indexEntry)
+		n.Countries[country] = entries
+	}
+	reserved := make(map[int]bool, len(entries))
+	for _, entry := range entries {
+		reserved[entry.Index] = true
+	}
+
+	result := make(map[*ProxieNode]int, len(nodes))
+	used := make(map[int]bool, len(nodes))
+	for _, node := range nodes {
+		entry, ok := entries[NodeIdentity(node)]
+		if !ok || used[entry.Index] {
+			continue
+		}
+		entry.LastSeen = now
+		result[node] = entry.Index
+		used[entry.Index] = true
+	}
+
+	next := 1
+	for _, node := range nodes {
+		if _, ok := result[node]; ok {
+			continue
+		}
+		for used[next] || reserved[next] {
+			next++
+		}
+		result[node] = next
+		used[next] = true
+		// 身份重复的节点不记录，下次运行重新分配
+		if id := NodeIdentity(node); entries[id] == nil {
+			entries[id] = &indexEntry{Index: next, LastSeen: now}
+		}
+	}
+	return result
+}
+
+func loadNodeIndexes(confId string) *nodeIndexes {
+	indexes := &nodeIndexes{}
+	if env.GetDB() == nil {
+		return indexes
+	}
+	key := models.NodeIndexKey{ConfId: confId}
+	saved, err := env.QueryDb[nodeIndexes](key.ToKey())
+	if err != nil {
+		if !errors.Is(err, badger.ErrKeyNotFound) {
+			slog.Warn("failed to load node indexes", "conf", confId, "error", err)
+		}
+		return indexes
+	}
+	return &saved
+}
+
+func saveNodeIndexes(confId string, indexes *nodeIndexes) {
+	db := env.GetDB()
+	if db == nil {
+		return
+	}
+	key := models.NodeIndexKey{ConfId: confId}
+	err := db.Update(func(txn *badger.Txn) error {
+		data, err := json.Marshal(indexes)
+		if err != nil {
+			return err
+		}
+		return txn.SetEntry(badger.NewEntry(key.ToKey(), data).WithTTL(nodeIndexTTL))
+	})
+	if err != nil {
+		slog.Warn("failed to save node indexes", "conf", confId, "error", err)
+	}
+}
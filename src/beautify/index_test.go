@@ -0,0 +1,73 @@
+package beautify
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_nodeIndexes_assign(t *testing.T) {
+	sub := &Subscription{SubName: "A"}
+	node := func(server string) *ProxieNode {
+		return &ProxieNode{
+			Name:         server,
+			Proxie:       map[string]any{"type": "vmess", "server": server, "port": 443},
+			Subscription: sub,
+		}
+	}
+	a, b, c, d := node("a"), node("b"), node("c"), node("d")
+	indexes := &nodeIndexes{}
+	now := time.Now()
+
+	check := func(got map[*ProxieNode]int, want map[*ProxieNode]int) {
+		t.Helper()
+		for n, index := range want {
+			if got[n] != index {
+				t.Errorf("index of %s = %d, want %d", n.Name, got[n], index)
+			}
+		}
+	}
+
+	check(indexes.assign("JP", []*ProxieNode{a, b, c}, now), map[*ProxieNode]int{a: 1, b: 2, c: 3})
+
+	// 排序变化后沿用原序号，b消失后其序号保留，新节点d使用新序号
+	now = now.Add(time.Hour)
+	check(indexes.assign("JP", []*ProxieNode{c, a, d}, now), map[*ProxieNode]int{c: 3, a: 1, d: 4})
+
+	// 节点改名但身份不变时沿用序号
+	renamed := node("c")
+	renamed.Name = "🇯🇵 日本 03 新名称"
+	check(indexes.assign("JP", []*ProxieNode{renamed}, now), map[*ProxieNode]int{renamed: 3})
+
+	// b消失超过保留时长后序号可被复用
+	now = now.Add(nodeIndexReserve)
+	indexes.prune(now)
+	e := node("e")
+	check(indexes.assign("JP", []*ProxieNode{a, c, d, e}, now), map[*ProxieNode]int{a: 1, c: 3, d: 4, e: 2})
+
+	// 不同国家独立分配
+	check(indexes.assign("US", []*ProxieNode{b}, now), map[*ProxieNode]int{b: 1})
+}
+
+func Test_nodeIndexes_assign_SharedServer(t *testing.T) {
+	// 中转节点共用 服务器:端口，按 ws path 区分，排序变化后序号仍指向同一节点
+	sub := &Subscription{SubName: "A"}
+	relay := func(path string) *ProxieNode {
+		return &ProxieNode{
+			Name:         path,
+			Proxie:       map[string]any{"type": "vmess", "server": "relay.example.com", "port": 443, "ws-opts": map[string]any{"path": path}},
+			Subscription: sub,
+		}
+	}
+	hk, jp := relay("/hk"), relay("/jp")
+	indexes := &nodeIndexes{}
+	now := time.Now()
+
+	first := indexes.assign("HK", []*ProxieNode{hk, jp}, now)
+	second := indexes.assign("HK", []*ProxieNode{jp, hk}, now.Add(time.Hour))
+	if first[hk] != 1 || first[jp] != 2 {
+		t.Fatalf("first assign = hk %d, jp %d, want 1, 2", first[hk], first[jp])
+	}
+	if second[hk] != first[hk] || second[jp] != first[jp] {
+		t.Errorf("after re-sort = hk %d, jp %d, want %d, %d", second[hk], second[jp], first[hk], first[jp])
+	}
+}
@@ -0,0 +1,158 @@
+package beautify
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/ocyss/sub-store-lab/src/env"
+	"github.com/ocyss/sub-store-lab/src/models"
+	"github.com/samber/lo"
+)
+
+const (
+	DropMaxPerCountry      = "max_per_country"
+	DropMaxPerSubscription = "max_per_subscription"
+	DropMaxTotal           = "max_total"
+)
+
+// keptNodesTTL 保留集合的有效期，超过后重新按排序选择
+const keptNodesTTL = time.Hour * 24 * 30
+
+// keptWindow 上次保留的节点排名在限制数量的该倍数以内时优先保留，避免名次小幅波动导致节点频繁进出
+const keptWindow = 2
+
+// NodeIdentity 节点身份，与节点名称无关: 订阅::连接参数指纹，
+// 中转机场的多个节点常共用 服务器:端口，靠 path/sni/uuid 等区分，需要全部连接参数才能区分
+func NodeIdentity(p *ProxieNode) string {
+	subName := ""
+	if p.Subscription != nil {
+		subName = p.Subscription.SubName
+	}
+	return subName + "::" + models.ProxieFingerprint(p.Proxie)
+}
+
+// selectNodes 按策略顺序选择节点，limit为总数量，perSub为每个订阅的数量，0为不限
+// 上次保留的节点排名在窗口内时优先选择，返回结果保持策略顺序
+func selectNodes(strategy SortStrategy, nodes []*ProxieNode, limit, perSub int, kept map[string]bool, drop func(node *ProxieNode, reason string)) []*ProxieNode {
+	if limit <= 0 && perSub <= 0 {
+		return nodes
+	}
+	sorted := append([]*ProxieNode(nil), nodes...)
+	sortNodes(strategy, sorted)
+
+	subRank := make(map[*ProxieNode]int, len(sorted))
+	subSeen := make(map[string]int)
+	for _, node := range sorted {
+		subRank[node] = subSeen[node.Subscription.SubName]
+		subSeen[node.Subscription.SubName]++
+	}
+
+	taken := make(map[*ProxieNode]bool, len(sorted))
+	subCount := make(map[string]int)
+	reason := func(node *ProxieNode) string {
+		if perSub > 0 && subCount[node.Subscription.SubName] >= perSub {
+			return DropMaxPerSubscription
+		}
+		if limit > 0 && len(taken) >= limit {
+			return DropMaxPerCountry
+		}
+		return ""
+	}
+	take := func(node *ProxieNode) {
+		taken[node] = true
+		subCount[node.Subscription.SubName]++
+	}
+
+	for i, node := range sorted {
+		if !kept[NodeIdentity(node)] {
+			continue
+		}
+		inWindow := (limit <= 0 || i < limit*keptWindow) && (perSub <= 0 || subRank[node] < perSub*keptWindow)
+		if inWindow && reason(node) == "" {
+			take(node)
+		}
+	}
+	for _, node := range sorted {
+		if taken[node] {
+			continue
+		}
+		if r := reason(node); r != "" {
+			drop(node, r)
+			continue
+		}
+		take(node)
+	}
+	return lo.Filter(sorted, func(node *ProxieNode, _ int) bool {
+		return taken[node]
+	})
+}
+
+// limitNodes 按国家、订阅及总数限制节点数量
+func limitNodes(conf *models.Conf, strategy SortStrategy, groups map[string]*CountryGroup, kept map[string]bool, report *Report) {
+	for _, group := range groups {
+		group.Nodes = selectNodes(strategy, group.Nodes, conf.MaxPerCountry, conf.MaxPerSubscription, kept, func(node *ProxieNode, reason string) {
+			report.drop(reason, node)
+		})
+	}
+	if conf.MaxTotal <= 0 {
+		return
+	}
+	all := lo.FlatMap(lo.Values(groups), func(group *CountryGroup, _ int) []*ProxieNode {
+		return group.Nodes
+	})
+	selected := lo.SliceToMap(selectNodes(strategy, all, conf.MaxTotal, 0, kept, func(node *ProxieNode, _ string) {
+		report.drop(DropMaxTotal, node)
+	}), func(node *ProxieNode) (*ProxieNode, bool) {
+		return node, true
+	})
+	for _, group := range groups {
+		group.Nodes = lo.Filter(group.Nodes, func(node *ProxieNode, _ int) bool {
+			return selected[node]
+		})
+	}
+}
+
+// limited 是否配置了数量限制
+func limited(conf *models.Conf) bool {
+	return conf.MaxPerCountry > 0 || conf.MaxPerSubscription > 0 || conf.MaxTotal > 0
+}
+
+func loadKeptNodes(confId string) map[string]bool {
+	if env.GetDB() == nil {
+		return nil
+	}
+	key := models.KeptNodesKey{ConfId: confId}
+	ids, err := env.QueryDb[[]string](key.ToKey())
+	if err != nil {
+		if !errors.Is(err, badger.ErrKeyNotFound) {
+			slog.Warn("failed to load kept nodes", "conf", confId, "error", err)
+		}
+		return nil
+	}
+	return lo.SliceToMap(ids, func(id string) (string, bool) {
+		return id, true
+	})
+}
+
+func saveKeptNodes(confId string, nodes []*ProxieNode) {
+	db := env.GetDB()
+	if db == nil {
+		return
+	}
+	key := models.KeptNodesKey{ConfId: confId}
+	err := db.Update(func(txn *badger.Txn) error {
+		data, err := json.Marshal(lo.Map(nodes, func(node *ProxieNode, _ int) string {
+			return NodeIdentity(node)
+		}))
+		if err != nil {
+			return err
+		}
+		return txn.SetEntry(badger.NewEntry(key.ToKey(), data).WithTTL(keptNodesTTL))
+	})
+	if err != nil {
+		slog.Warn("failed to save kept nodes", "conf", confId, "error", err)
+	}
+}
@@ -0,0 +1,110 @@
+package beautify
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/samber/lo"
+)
+
+func Test_selectNodes(t *testing.T) {
+	subA, subB := &Subscription{SubName: "A"}, &Subscription{SubName: "B"}
+	node := func(name string, sub *Subscription, delay uint16) *ProxieNode {
+		n := testNode(name, delay, 0, nil, 0)
+		n.Subscription = sub
+		n.Proxie = map[string]any{"type": "ss", "server": name + ".example.com", "port": 443}
+		return n
+	}
+	nodes := []*ProxieNode{
+		node("a1", subA, 100),
+		node("a2", subA, 110),
+		node("a3", subA, 120),
+		node("b1", subB, 130),
+		node("b2", subB, 140),
+		node("a4", subA, 500),
+	}
+	kept := func(names ...string) map[string]bool {
+		return lo.SliceToMap(names, func(name string) (string, bool) {
+			n, _ := lo.Find(nodes, func(n *ProxieNode) bool { return n.Name == name })
+			return NodeIdentity(n), true
+		})
+	}
+
+	tests := []struct {
+		name      string
+		limit     int
+		perSub    int
+		kept      map[string]bool
+		want      []string
+		wantDrops map[string]int
+	}{
+		{
+			name: "unlimited",
+			want: []string{"a1", "a2", "a3", "b1", "b2", "a4"},
+		},
+		{
+			name:      "per country",
+			limit:     2,
+			want:      []string{"a1", "a2"},
+			wantDrops: map[string]int{DropMaxPerCountry: 4},
+		},
+		{
+			name:      "per subscription",
+			limit:     3,
+			perSub:    2,
+			want:      []string{"a1", "a2", "b1"},
+			wantDrops: map[string]int{DropMaxPerSubscription: 2, DropMaxPerCountry: 1},
+		},
+		{
+			name:      "kept within window",
+			limit:     2,
+			kept:      kept("a3"),
+			want:      []string{"a1", "a3"},
+			wantDrops: map[string]int{DropMaxPerCountry: 4},
+		},
+		{
+			name:      "kept outside window",
+			limit:     2,
+			kept:      kept("a4"),
+			want:      []string{"a1", "a2"},
+			wantDrops: map[string]int{DropMaxPerCountry: 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drops := make(map[string]int)
+			got := selectNodes(delayStrategy{}, nodes, tt.limit, tt.perSub, tt.kept, func(_ *ProxieNode, reason string) {
+				drops[reason]++
+			})
+			if names := lo.Map(got, func(n *ProxieNode, _ int) string { return n.Name }); !slices.Equal(names, tt.want) {
+				t.Errorf("selectNodes() = %v, want %v", names, tt.want)
+			}
+			if len(drops) != len(tt.wantDrops) {
+				t.Errorf("drops = %v, want %v", drops, tt.wantDrops)
+			}
+			for reason, count := range tt.wantDrops {
+				if drops[reason] != count {
+					t.Errorf("drops[%s] = %d, want %d", reason, drops[reason], count)
+				}
+			}
+		})
+	}
+}
+
+func Test_selectNodes_SharedServer(t *testing.T) {
+	// 中转节点共用 服务器:端口，按 ws path 区分，只有上次保留的节点优先
+	sub := &Subscription{SubName: "A"}
+	relay := func(name string, delay uint16) *ProxieNode {
+		n := testNode(name, delay, 0, nil, 0)
+		n.Subscription = sub
+		n.Proxie = map[string]any{"type": "vmess", "server": "relay.example.com", "port": 443, "ws-opts": map[string]any{"path": "/" + name}}
+		return n
+	}
+	nodes := []*ProxieNode{relay("hk", 100), relay("jp", 110), relay("sg", 120)}
+	kept := map[string]bool{NodeIdentity(nodes[1]): true}
+
+	got := selectNodes(delayStrategy{}, nodes, 1, 0, kept, func(*ProxieNode, string) {})
+	if names := lo.Map(got, func(n *ProxieNode, _ int) string { return n.Name }); !slices.Equal(names, []string{"jp"}) {
+		t.Errorf("selectNodes() = %v, want [jp]", names)
+	}
+}
@@ -5,33 +5,77 @@ import (
 	"log/slog"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/ocyss/sub-store-lab/src/models"
+	"github.com/ocyss/sub-store-lab/src/notify"
+	"github.com/ocyss/sub-store-lab/src/utils"
 	"github.com/samber/lo"
 )
 
 type CountryGroup struct {
 	Country string
-	Delay   float64
+	Score   float64 // 排序策略计算的分值，越小越靠前
 	Nodes   []*ProxieNode
 }
 
-func (c *CountryGroup) SetDelay(delay float64) {
-	c.Delay = delay
+func (c *CountryGroup) SetScore(score float64) {
+	c.Score = score
 }
 
 func (c *CountryGroup) AddNode(node *ProxieNode) {
 	c.Nodes = append(c.Nodes, node)
 }
 
-func ProcessNodes(conf *models.Conf, subs map[string]*Subscription) (result []map[string]any) {
+// ProcessNodes 处理并重命名节点，conf.ProxyGroups 为真时同时返回按国家等生成的策略组
+func ProcessNodes(conf *models.Conf, subs map[string]*Subscription) (result []map[string]any, groups []map[string]any) {
 	if len(subs) == 0 {
-		return nil
+		return nil, nil
 	}
 	subs = lo.PickBy(subs, func(_ string, sub *Subscription) bool {
 		return len(sub.Nodes) > 0
 	})
 
+	report := &Report{ConfId: conf.Id, At: time.Now(), Filter: conf.Filter}
+	defer reports.Store(conf.Id, report)
+
+	// 配置错误时使用默认行为，并通过提示节点返回错误
+	strategy, err := NewSortStrategy(&conf.Sort)
+	if err != nil {
+		slog.Warn("排序配置无效, 按延迟排序", "error", err)
+		strategy = delayStrategy{}
+	}
+
+	tmpl, err := newNameTemplate(conf.NameTemplate)
+	if err != nil {
+		slog.Warn("name_template无效, 使用默认格式", "error", err)
+		report.notice(err)
+		tmpl, _ = newNameTemplate("")
+	}
+	mismatchAction, err := parseMismatchAction(conf.MismatchAction)
+	if err != nil {
+		slog.Warn("mismatch_action无效, 仅标记", "error", err)
+		report.notice(err)
+	}
+	filter, err := ParseFilter(conf.Filter)
+	if err != nil {
+		slog.Warn("filter无效, 不过滤节点", "error", err)
+		report.notice(fmt.Errorf("解析filter失败: %w", err))
+	}
+
+	dedup, err := newDeduper(&conf.Dedup, strategy)
+	if err != nil {
+		slog.Warn("dedup配置无效, 不去重", "error", err)
+		report.notice(fmt.Errorf("解析dedup失败: %w", err))
+	}
+
+	infoPatterns, err := newInfoPatterns(conf.InfoPatterns)
+	if err != nil {
+		slog.Warn("info_patterns无效, 使用默认值", "error", err)
+		report.notice(err)
+		infoPatterns = defaultInfoPatterns
+	}
+
 	subscriptionSort := make([]string, 0, len(subs))
 
 	countryGroup := make(map[string]*CountryGroup)
@@ -40,23 +84,54 @@ func ProcessNodes(conf *models.Conf, subs map[string]*Subscription) (result []ma
 	// 根据国家进行分组, 并记录订阅num, 提取info节点
 	for _, sub := range subs {
 		subscriptionSort = append(subscriptionSort, sub.SubName)
-		result = append(result, sub.ExtractInfoNode())
+		result = append(result, sub.ExtractInfoNode(infoPatterns))
 
 		for _, node := range sub.Nodes {
 			node.Subscription = sub
-			if node.Purity.Country != nil {
-				country := *node.Purity.Country
-				if _, ok := countryGroup[country]; !ok {
-					countryGroup[country] = &CountryGroup{
-						Country: country,
-						Nodes:   make([]*ProxieNode, 0),
-					}
+			report.Total++
+			if node.Purity.Country == nil {
+				report.drop(DropNoCountry, node)
+				continue
+			}
+			country := node.checkCountry(mismatchAction)
+			// 丢弃名称声明的国家与检测结果不一致的节点
+			if node.CountryMismatch && mismatchAction == models.MismatchActionDrop {
+				slog.Debug("节点国家不一致, 丢弃", "name", node.Name, "claimed", node.ClaimedCountry, "detected", *node.Purity.Country)
+				report.drop(DropMismatch, node)
+				continue
+			}
+			// 过滤无延迟节点
+			if node.Delay == 0 {
+				report.drop(DropNoDelay, node)
+				continue
+			}
+			// 过滤隔离中的节点
+			if conf.DropQuarantined && node.Quarantined {
+				report.drop(DropQuarantined, node)
+				continue
+			}
+			// 按过滤表达式丢弃，记录第一个不满足的子句
+			if filter != nil {
+				if ok, clause := filter.Match(node); !ok {
+					report.drop("filter: "+filter.Clauses[clause].Text, node)
+					continue
+				}
+			}
+			if _, ok := countryGroup[country]; !ok {
+				countryGroup[country] = &CountryGroup{
+					Country: country,
+					Nodes:   make([]*ProxieNode, 0),
 				}
-				countryGroup[country].AddNode(node)
 			}
+			countryGroup[country].AddNode(node)
 		}
 	}
 
+	// 保存订阅信息，并在即将到期或流量不足时告警
+	states := subscriptionStates(conf.Id, subs, time.Now())
+	saveSubscriptionStates(states)
+	go notify.CheckSubscriptions(conf.Alert, states)
+
 	// 按订阅num或者订阅名名排序
 	sort.Slice(subscriptionSort, func(i, j int) bool {
 		if subs[subscriptionSort[i]].SubNameNum == subs[subscriptionSort[j]].SubNameNum {
@@ -65,57 +140,126 @@ func ProcessNodes(conf *models.Conf, subs map[string]*Subscription) (result []ma
 		return subs[subscriptionSort[i]].SubNameNum < subs[subscriptionSort[j]].SubNameNum
 	})
 
-	// 统计延迟，并按平均延迟排序国家组（从低到高）
-	for _, group := range countryGroup {
-		sort.Slice(group.Nodes, func(i, j int) bool {
-			return group.Nodes[i].Delay < group.Nodes[j].Delay
+	// 跨订阅去重，多个订阅共享同一服务器或出口时只保留一个订阅的副本
+	if dedup != nil && len(dedup.by) > 0 {
+		dedup.subOrder = lo.SliceToMap(lo.Range(len(subscriptionSort)), func(i int) (string, int) {
+			return subscriptionSort[i], i
 		})
-		// 只取前3个节点计算平均值, 避免高延迟节点污染整个组
-		topN := min(3, len(group.Nodes))
-		delays := lo.Reduce(group.Nodes[:topN], func(agg float64, node *ProxieNode, _ int) float64 {
-			return agg + float64(node.Delay)
-		}, 0.0)
-		group.SetDelay(delays / float64(len(group.Nodes)))
-		countryGroupSort = append(countryGroupSort, group.Country)
+		all := lo.FlatMap(lo.Values(countryGroup), func(group *CountryGroup, _ int) []*ProxieNode {
+			return group.Nodes
+		})
+		remaining, shared := dedup.dedup(all, func(node *ProxieNode, reason string) {
+			report.drop(reason, node)
+		})
+		report.Shared = shared
+		keep := lo.SliceToMap(remaining, func(node *ProxieNode) (*ProxieNode, bool) {
+			return node, true
+		})
+		for country, group := range countryGroup {
+			group.Nodes = lo.Filter(group.Nodes, func(node *ProxieNode, _ int) bool {
+				return keep[node]
+			})
+			if len(group.Nodes) == 0 {
+				delete(countryGroup, country)
+			}
+		}
 	}
 
-	sort.Slice(countryGroupSort, func(i, j int) bool {
-		return countryGroup[countryGroupSort[i]].Delay < countryGroup[countryGroupSort[j]].Delay
-	})
+	// 按策略计算国家组分值，固定顺序的国家在前，其余按分值排序（从低到高）
+	scores := make(map[string]float64, len(countryGroup))
+	for _, group := range countryGroup {
+		scores[group.Country] = groupScore(strategy, group.Nodes)
+		group.SetScore(scores[group.Country])
+		countryGroupSort = append(countryGroupSort, group.Country)
+	}
+	countryGroupSort = orderCountries(countryGroupSort, scores, conf.Sort.CountryOrder)
 
-	slog.Info("国家组平均延迟", "sorted", lo.Map(countryGroupSort, func(item string, _ int) any {
-		return fmt.Sprintf("%s: %.2fms", item, countryGroup[item].Delay)
+	slog.Info("国家组排序", "strategy", strategy.Name(), "sorted", lo.Map(countryGroupSort, func(item string, _ int) any {
+		return fmt.Sprintf("%s: %.2f", item, countryGroup[item].Score)
 	}))
 
-	// 记录国家组序号，保证唯一
-	countryNum := make(map[string]int)
+	// 按数量限制选择节点，优先保留上次保留的节点
+	if limited(conf) {
+		limitNodes(conf, strategy, countryGroup, loadKeptNodes(conf.Id), report)
+	}
+
+	// 节点序号按身份持久化，刷新后同一序号仍指向同一服务器
+	now := time.Now()
+	indexes := loadNodeIndexes(conf.Id)
+	indexes.prune(now)
+	keptNodes := make([]*ProxieNode, 0)
+	groupNames := make(map[string][]string, len(countryGroupSort))
 	keywords := strings.Split(conf.KeywordKeep, "|")
+	var names uniqueNames
 	for _, gkey := range countryGroupSort {
 		group := countryGroup[gkey]
-		country := group.Country
 		// 按订阅名进一步分组, 根据订阅num实现国家组内订阅有序
 		subNodeGroups := lo.GroupBy(group.Nodes, func(node *ProxieNode) string {
 			return node.Subscription.SubName
 		})
+		groupNodes := make([]*ProxieNode, 0, len(group.Nodes))
 		for _, subName := range subscriptionSort {
 			subNodes := subNodeGroups[subName]
-			// 对组内节点进行排序（按延迟）
-			sort.Slice(subNodes, func(i, j int) bool {
-				return subNodes[i].Delay < subNodes[j].Delay
-			})
-			for _, node := range subNodes {
-				// 过滤无延迟节点
-				if node.Delay == 0 {
-					continue
-				}
-				countryNum[country]++
-				index := countryNum[country]
-				result = append(result, node.Format(keywords, index))
-			}
+			// 对组内节点按策略排序
+			sortNodes(strategy, subNodes)
+			groupNodes = append(groupNodes, subNodes...)
 		}
+		groupIndexes := indexes.assign(group.Country, groupNodes, now)
+		for _, node := range groupNodes {
+			report.Kept++
+			keptNodes = append(keptNodes, node)
+			proxie := node.Format(tmpl, keywords, groupIndexes[node])
+			names.apply(proxie)
+			result = append(result, proxie)
+		}
+		groupNames[gkey] = nodeNames(groupNodes, func(*ProxieNode) bool { return true })
 	}
+	saveNodeIndexes(conf.Id, indexes)
+
+	if limited(conf) {
+		saveKeptNodes(conf.Id, keptNodes)
+	}
+
+	if conf.ProxyGroups {
+		labGroups, err := loadLabGroups()
+		if err == nil {
+			groups, err = labGroups.build(countryGroupSort, groupNames, keptNodes)
+		}
+		if err != nil {
+			slog.Warn("生成策略组失败", "error", err)
+			report.notice(err)
+		}
+	}
+
+	if tmpl.err != nil {
+		slog.Warn("name_template执行失败, 部分节点使用默认格式", "error", tmpl.err)
+		report.notice(tmpl.err)
+	}
+	if err := names.err(); err != nil {
+		slog.Warn("节点名称重复", "error", err)
+		report.notice(err)
+	}
+	notices := lo.Map(report.Notices, func(notice string, _ int) map[string]any {
+		return noticeNode(subs, notice)
+	})
+	result = append(notices, result...)
 
 	return lo.Filter(result, func(item map[string]any, _ int) bool {
 		return item != nil
-	})
+	}), groups
+}
+
+// noticeNode 复制任一节点作为提示节点，名称为提示信息
+func noticeNode(subs map[string]*Subscription, notice string) map[string]any {
+	for _, sub := range subs {
+		for _, n := range sub.Nodes {
+			var node map[string]any
+			if utils.DeepCopy(n.Proxie, &node) != nil {
+				return nil
+			}
+			node["name"] = "⚠️" + notice
+			return node
+		}
+	}
+	return nil
 }
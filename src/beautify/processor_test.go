@@ -0,0 +1,85 @@
+package beautify
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/ocyss/sub-store-lab/src/models"
+	"github.com/samber/lo"
+)
+
+func TestProcessNodes(t *testing.T) {
+	node := func(sub *Subscription, name, country, server string, delay uint16) *ProxieNode {
+		n := sub.AddNode(map[string]any{"name": name, "type": "ss", "server": server, "port": 443, "password": "pw"})
+		n.Delay = delay
+		if country != "" {
+			n.Purity.Country = lo.ToPtr(country)
+			n.Purity.CountryFlag = country
+		}
+		return n
+	}
+	subA := &Subscription{SubName: "A", SubNameNum: 1}
+	subB := &Subscription{SubName: "B", SubNameNum: 2}
+	node(subA, "日本 01", "HK", "a1.example.com", 100)
+	node(subA, "香港 02", "HK", "a2.example.com", 0)
+	node(subA, "香港 03", "HK", "a3.example.com", 110).SetQuarantined()
+	node(subA, "香港 04", "HK", "a4.example.com", 900)
+	node(subA, "香港 05", "HK", "shared.example.com", 120)
+	node(subA, "香港 06", "HK", "a6.example.com", 130)
+	node(subA, "香港 07", "HK", "a7.example.com", 140)
+	node(subA, "未知 08", "", "a8.example.com", 100)
+	node(subB, "香港 B1", "HK", "shared.example.com", 150)
+	node(subB, "日本 B2", "JP", "b2.example.com", 90)
+
+	conf := models.DefaultConf()
+	conf.Id = t.Name()
+	conf.MismatchAction = models.MismatchActionDrop
+	conf.DropQuarantined = true
+	conf.Filter = "delay < 500"
+	conf.Dedup.By = []string{models.DedupByFingerprint}
+	conf.MaxPerCountry = 2
+	conf.NameTemplate = "{{.Country}}_{{.Index}} {{.OldName}}"
+
+	result, _ := ProcessNodes(conf, map[string]*Subscription{"A": subA, "B": subB})
+
+	// 订阅信息节点在前且顺序不固定，仅比较处理后的节点
+	names := lo.FilterMap(result, func(proxie map[string]any, _ int) (string, bool) {
+		_, ok := proxie["_lab_old_name"]
+		return proxie["name"].(string), ok
+	})
+	if want := []string{"JP_1 日本 B2", "HK_1 香港 05", "HK_2 香港 06"}; !slices.Equal(names, want) {
+		t.Errorf("names = %v, want %v", names, want)
+	}
+
+	reports := GetReports(conf.Id)
+	if len(reports) != 1 {
+		t.Fatalf("GetReports() = %d reports, want 1", len(reports))
+	}
+	report := reports[0]
+	if report.Total != 10 || report.Kept != 3 {
+		t.Errorf("Total = %d, Kept = %d, want 10, 3", report.Total, report.Kept)
+	}
+	if len(report.Notices) != 0 {
+		t.Errorf("Notices = %v, want none", report.Notices)
+	}
+	drops := make(map[string][]string)
+	for _, d := range report.Drops {
+		if d.Count != len(d.Nodes) {
+			t.Errorf("%s: Count = %d, Nodes = %v", d.Reason, d.Count, d.Nodes)
+		}
+		drops[d.Reason] = d.Nodes
+	}
+	wantDrops := map[string][]string{
+		DropMismatch:             {"日本 01"},
+		DropNoDelay:              {"香港 02"},
+		DropQuarantined:          {"香港 03"},
+		"filter: delay < 500":    {"香港 04"},
+		DropNoCountry:            {"未知 08"},
+		DropDuplicateFingerprint: {"香港 B1"},
+		DropMaxPerCountry:        {"香港 07"},
+	}
+	if !maps.EqualFunc(drops, wantDrops, slices.Equal) {
+		t.Errorf("Drops = %v, want %v", drops, wantDrops)
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/ocyss/sub-store-lab/src/models"
 	"github.com/ocyss/sub-store-lab/src/tester"
+	"github.com/ocyss/sub-store-lab/src/tester/purity"
 	"github.com/samber/lo"
 )
 
@@ -18,53 +20,130 @@ type ProxieNode struct {
 	Speed  tester.SpeedResult
 	Purity tester.PurityResult
 
+	Quarantined bool    // 任一测试连续失败被隔离
+	Stability   float64 // 延迟历史稳定性，0-1
+
+	ClaimedCountry  string // 节点名称中声明的国家
+	CountryMismatch bool   // 声明的国家与检测到的国家不一致
+	Regrouped       bool   // 按声明的国家分组及命名
+
+	SharedWith []string // 与该节点共享服务器或出口的其他订阅，其副本已去重
+
 	Subscription *Subscription `json:"-"`
 }
 
+// mismatchMarker 节点名称声明的国家与检测到的国家不一致时的标记
+const mismatchMarker = "❗"
+
+// checkCountry 解析节点名称中声明的国家并与检测结果比较，返回分组使用的国家
+func (p *ProxieNode) checkCountry(action string) string {
+	p.ClaimedCountry, _ = ParseClaimedCountry(p.Name)
+	p.CountryMismatch = countryMismatch(p.ClaimedCountry, lo.FromPtr(p.Purity.Country))
+	p.Regrouped = p.CountryMismatch && action == models.MismatchActionRegroup
+	if p.Regrouped {
+		return p.ClaimedCountry
+	}
+	return lo.FromPtr(p.Purity.Country)
+}
+
 // 格式化节点名称，添加序号确保唯一性
-func (p *ProxieNode) Format(words []string, index int) map[string]any {
-	// 基本格式: [旗帜]国家_序号🏠速率[关键词][0.5x]🩵订阅名
+func (p *ProxieNode) Format(tmpl *nameTemplate, words []string, index int) map[string]any {
+	facts := p.facts(words, index)
+	if facts == nil {
+		return p.Proxie
+	}
+	if p.CountryMismatch {
+		p.Proxie["_lab_claimed_country"] = p.ClaimedCountry
+		p.Proxie["_lab_country_mismatch"] = true
+	}
+
+	if len(p.SharedWith) > 0 {
+		p.Proxie["_lab_shared_subs"] = p.SharedWith
+	}
 
+	p.Proxie["_lab_old_name"] = p.Proxie["name"]
+	if stale := p.staleResults(); len(stale) > 0 {
+		p.Proxie["_lab_stale"] = stale
+	}
+
+	p.Proxie["name"] = tmpl.Execute(facts)
+
+	return p.Proxie
+}
+
+// facts 收集节点名称模板使用的字段，缺少国家信息时返回nil
+func (p *ProxieNode) facts(words []string, index int) *NodeFacts {
 	countryFlag := p.Purity.CountryFlag
 	if countryFlag == "" {
-		return p.Proxie
+		return nil
 	}
 
 	countryCode := lo.FromPtr(p.Purity.Country)
 	if countryCode == "" {
-		return p.Proxie
+		return nil
+	}
+	if p.Regrouped {
+		countryCode = p.ClaimedCountry
+		countryFlag = purity.GetCountryFlag(&countryCode)
 	}
 
-	keywords := strings.Join(lo.Reduce(words, func(agg []string, item string, _ int) []string {
-		if strings.Contains(p.Name, item) {
+	keywords := lo.Reduce(words, func(agg []string, item string, _ int) []string {
+		if item != "" && strings.Contains(p.Name, item) {
 			return append(agg, item)
 		}
 		return agg
-	}, []string{}), "|")
-
-	rate := getRate(p.Name)
-
-	p.Proxie["_lab_old_name"] = p.Proxie["name"]
-
-	p.Proxie["name"] = fmt.Sprintf("%s%s_%d%s%s%s%s%s%s",
-		countryFlag,                         // 国旗
-		countryCode,                         // 国家代码
-		index,                               // 序号
-		p.Purity.TypeIcon,                   // 类型图标
-		lo.FromPtrOr(p.Speed.Speed, "-1KB"), // 速度
-		lo.Ternary(keywords != "", fmt.Sprintf("[%s]", keywords), ""), // 关键词
-		rate,                   // 倍率
-		p.Purity.PurityIcon,    // 纯净度图标
-		p.Subscription.SubName, // 订阅名
-	)
+	}, []string{})
+
+	return &NodeFacts{
+		Flag:           countryFlag,
+		Country:        countryCode,
+		Mismatch:       lo.Ternary(p.CountryMismatch, mismatchMarker, ""),
+		ClaimedCountry: p.ClaimedCountry,
+		Index:          index,
+		Delay:          int(p.Delay),
+		Speed:          lo.FromPtrOr(p.Speed.Speed, "-1KB"),
+		SpeedMbps:      p.Speed.SpeedMbps,
+		RiskScore:      lo.FromPtrOr(p.Purity.RiskScore, -1),
+		PurityIcon:     p.Purity.PurityIcon,
+		TypeIcon:       p.Purity.TypeIcon,
+		UsageType:      string(lo.FromPtr(p.Purity.UsageType)),
+		IP:             lo.FromPtr(p.Purity.IP),
+		City:           lo.FromPtr(p.Purity.City),
+		Region:         lo.FromPtr(p.Purity.Region),
+		ASN:            lo.FromPtr(p.Purity.ASN),
+		Org:            lo.FromPtr(p.Purity.Org),
+		Rate:           getRate(p.Name),
+		Keywords:       keywords,
+		Sub:            p.Subscription.SubName,
+		OldName:        p.Name,
+		Stale:          p.Purity.Stale || p.Speed.Stale,
+	}
+}
 
-	return p.Proxie
+// staleResults 返回已过期但仍在使用的测试结果名称
+func (p *ProxieNode) staleResults() []string {
+	var stale []string
+	if p.Purity.Stale {
+		stale = append(stale, "Purity")
+	}
+	if p.Speed.Stale {
+		stale = append(stale, "Speed")
+	}
+	return stale
 }
 
 func (p *ProxieNode) SetDelay(delay uint16) {
 	p.Delay = delay
 }
 
+func (p *ProxieNode) SetStability(stability float64) {
+	p.Stability = stability
+}
+
+func (p *ProxieNode) SetQuarantined() {
+	p.Quarantined = true
+}
+
 func getRate(name string) string {
 	matches := reNodeRate.FindStringSubmatch(name)
 	// matches[1] for bracketed, matches[2] for plain
@@ -0,0 +1,161 @@
+package beautify
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ocyss/sub-store-lab/src/models"
+)
+
+// regionNames 国家/地区的中英文名称及常见城市，按名称长度优先匹配，如 "印度尼西亚" 先于 "印度"
+var regionNames = map[string][]string{
+	"HK": {"中国香港", "香港", "Hong Kong", "HongKong"},
+	"MO": {"中国澳门", "澳门", "澳門", "Macau", "Macao"},
+	"TW": {"中国台湾", "台湾", "台灣", "台北", "新北", "彰化", "Taiwan", "Taipei"},
+	// 上海/广州等多为中转入口，不作为声明的国家
+	"CN": {"中国", "回国", "China"},
+	"JP": {"日本", "东京", "東京", "大阪", "京都", "埼玉", "Japan", "Tokyo", "Osaka"},
+	"KR": {"韩国", "韓國", "首尔", "首爾", "春川", "Korea", "Seoul"},
+	"SG": {"新加坡", "狮城", "Singapore"},
+	"US": {"美国", "美國", "洛杉矶", "圣何塞", "硅谷", "纽约", "西雅图", "芝加哥", "达拉斯", "凤凰城", "波特兰", "迈阿密", "亚特兰大", "弗吉尼亚",
+		"United States", "Los Angeles", "San Jose", "Silicon Valley", "New York", "Seattle", "Chicago", "Dallas", "Phoenix", "Miami", "Atlanta"},
+	"CA": {"加拿大", "多伦多", "蒙特利尔", "温哥华", "Canada", "Toronto", "Montreal", "Vancouver"},
+	"GB": {"英国", "英國", "伦敦", "曼彻斯特", "United Kingdom", "Britain", "England", "London"},
+	"DE": {"德国", "德國", "法兰克福", "Germany", "Frankfurt"},
+	"FR": {"法国", "法國", "巴黎", "France", "Paris"},
+	"NL": {"荷兰", "荷蘭", "阿姆斯特丹", "Netherlands", "Amsterdam"},
+	"IT": {"意大利", "米兰", "Italy", "Milan"},
+	"ES": {"西班牙", "马德里", "Spain", "Madrid"},
+	"CH": {"瑞士", "苏黎世", "Switzerland", "Zurich"},
+	"SE": {"瑞典", "Sweden"},
+	"FI": {"芬兰", "Finland"},
+	"NO": {"挪威", "Norway"},
+	"IE": {"爱尔兰", "Ireland", "Dublin"},
+	"PL": {"波兰", "Poland"},
+	"AT": {"奥地利", "Austria"},
+	"UA": {"乌克兰", "Ukraine"},
+	"RU": {"俄罗斯", "莫斯科", "Russia", "Moscow"},
+	"TR": {"土耳其", "伊斯坦布尔", "Turkey", "Istanbul"},
+	"IL": {"以色列", "Israel"},
+	"AE": {"阿联酋", "迪拜", "UAE", "Dubai"},
+	"IN": {"印度", "孟买", "India", "Mumbai"},
+	"ID": {"印度尼西亚", "印尼", "雅加达", "Indonesia", "Jakarta"},
+	"MY": {"马来西亚", "吉隆坡", "Malaysia", "Kuala Lumpur"},
+	"TH": {"泰国", "曼谷", "Thailand", "Bangkok"},
+	"VN": {"越南", "胡志明", "河内", "Vietnam", "Hanoi"},
+	"PH": {"菲律宾", "马尼拉", "Philippines", "Manila"},
+	"KZ": {"哈萨克斯坦", "Kazakhstan"},
+	"AU": {"澳大利亚", "澳洲", "悉尼", "墨尔本", "Australia", "Sydney", "Melbourne"},
+	"NZ": {"新西兰", "New Zealand"},
+	"BR": {"巴西", "圣保罗", "Brazil", "Sao Paulo"},
+	"AR": {"阿根廷", "Argentina"},
+	"CL": {"智利", "Chile"},
+	"MX": {"墨西哥", "Mexico"},
+	"ZA": {"南非", "约翰内斯堡", "South Africa"},
+	"EG": {"埃及", "Egypt"},
+	"NG": {"尼日利亚", "Nigeria"},
+}
+
+// regionCodes 名称中常见的大写代码，需以非字母分隔，不含 GB 避免与流量单位混淆
+var regionCodes = map[string]string{
+	"HK": "HK", "MO": "MO", "TW": "TW", "JP": "JP", "KR": "KR", "SG": "SG",
+	"US": "US", "USA": "US", "CA": "CA", "UK": "GB", "DE": "DE", "FR": "FR", "NL": "NL",
+	"RU": "RU", "TR": "TR", "IN": "IN", "AU": "AU", "MY": "MY", "TH": "TH", "VN": "VN", "PH": "PH",
+}
+
+type regionPattern struct {
+	code string
+	re   *regexp.Regexp
+}
+
+var regionPatterns = func() []regionPattern {
+	patterns := make([]regionPattern, 0)
+	for code, names := range regionNames {
+		for _, name := range names {
+			expr := regexp.QuoteMeta(name)
+			if utf8.RuneCountInString(name) == len(name) {
+				// 英文名称不区分大小写，需以非字母分隔，避免 India 匹配 Indiana
+				expr = `(?i)(?:^|[^a-zA-Z])(` + expr + `)(?:[^a-zA-Z]|$)`
+			} else {
+				expr = `(` + expr + `)`
+			}
+			patterns = append(patterns, regionPattern{code: code, re: regexp.MustCompile(expr)})
+		}
+	}
+	for name, code := range regionCodes {
+		patterns = append(patterns, regionPattern{
+			code: code,
+			re:   regexp.MustCompile(`(?:^|[^a-zA-Z])(` + name + `)(?:[^a-zA-Z]|$)`),
+		})
+	}
+	// 同一位置匹配多个名称时取最长的名称
+	slices.SortStableFunc(patterns, func(a, b regionPattern) int {
+		return len(b.re.String()) - len(a.re.String())
+	})
+	return patterns
+}()
+
+// flagCountry 解析旗帜emoji为国家代码
+func flagCountry(name string) (string, bool) {
+	runes := []rune(name)
+	for i := 0; i+1 < len(runes); i++ {
+		a, b := runes[i], runes[i+1]
+		if isRegionalIndicator(a) && isRegionalIndicator(b) {
+			return string([]rune{'A' + (a - 0x1F1E6), 'A' + (b - 0x1F1E6)}), true
+		}
+	}
+	return "", false
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+// ParseClaimedCountry 从节点名称中解析声明的国家/地区代码，旗帜优先，否则取最靠前的名称，
+// 中转节点名称常带有 中国移动、回国 等入口信息，同时命中其他国家时优先其他国家
+func ParseClaimedCountry(name string) (string, bool) {
+	if code, ok := flagCountry(name); ok {
+		if code == "UK" {
+			code = "GB"
+		}
+		return code, true
+	}
+	best, bestPos := "", -1
+	for _, p := range regionPatterns {
+		loc := p.re.FindStringSubmatchIndex(name)
+		if loc == nil {
+			continue
+		}
+		pos := loc[2]
+		switch {
+		case bestPos == -1,
+			best == "CN" && p.code != "CN",
+			pos < bestPos && (p.code != "CN" || best == "CN"):
+			best, bestPos = p.code, pos
+		}
+	}
+	return best, bestPos != -1
+}
+
+// parseMismatchAction 校验 mismatch_action，为空或无效时使用 mark
+func parseMismatchAction(action string) (string, error) {
+	switch action = strings.ToLower(strings.TrimSpace(action)); action {
+	case "":
+		return models.MismatchActionMark, nil
+	case models.MismatchActionMark, models.MismatchActionDrop, models.MismatchActionRegroup:
+		return action, nil
+	default:
+		return models.MismatchActionMark, fmt.Errorf("未知的mismatch_action: %s, 使用mark", action)
+	}
+}
+
+// countryMismatch 声明的国家与检测到的国家是否不一致，任一未知时视为一致
+func countryMismatch(claimed string, detected string) bool {
+	if claimed == "" || detected == "" {
+		return false
+	}
+	return !strings.EqualFold(claimed, detected)
+}
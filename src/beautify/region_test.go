@@ -0,0 +1,63 @@
+package beautify
+
+import (
+	"testing"
+
+	"github.com/ocyss/sub-store-lab/src/models"
+)
+
+func TestParseClaimedCountry(t *testing.T) {
+	tests := []struct {
+		name   string
+		node   string
+		want   string
+		wantOk bool
+	}{
+		{name: "flag", node: "🇯🇵日本|福利家宽softbank", want: "JP", wantOk: true},
+		{name: "flag wins over name", node: "🇺🇸 香港中转 美国", want: "US", wantOk: true},
+		{name: "chinese name", node: "新加坡 01 | 0.5x", want: "SG", wantOk: true},
+		{name: "chinese city", node: "洛杉矶 IPLC 专线", want: "US", wantOk: true},
+		{name: "longest name at same position", node: "印度尼西亚-雅加达", want: "ID", wantOk: true},
+		{name: "india", node: "印度 孟买 01", want: "IN", wantOk: true},
+		{name: "china prefix region", node: "中国香港 HKT", want: "HK", wantOk: true},
+		{name: "english name", node: "Hong Kong 02", want: "HK", wantOk: true},
+		{name: "english city lowercase", node: "tokyo-premium", want: "JP", wantOk: true},
+		{name: "english word boundary", node: "Indiana relay", want: "", wantOk: false},
+		{name: "code", node: "UK-London-03", want: "GB", wantOk: true},
+		{name: "earliest wins", node: "日本 to 美国", want: "JP", wantOk: true},
+		{name: "no region", node: "剩余流量：123.45 GB", want: "", wantOk: false},
+		{name: "entry city", node: "上海-日本 IEPL 01", want: "JP", wantOk: true},
+		{name: "entry city arrow", node: "广州→香港 IPLC", want: "HK", wantOk: true},
+		{name: "china carrier", node: "中国移动 香港 01", want: "HK", wantOk: true},
+		{name: "back to china", node: "回国 上海电信 01", want: "CN", wantOk: true},
+		{name: "entry city only", node: "深圳 01", want: "", wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseClaimedCountry(tt.node)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("ParseClaimedCountry(%q) = %s, %v, want %s, %v", tt.node, got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func Test_parseMismatchAction(t *testing.T) {
+	tests := []struct {
+		action  string
+		want    string
+		wantErr bool
+	}{
+		{"", models.MismatchActionMark, false},
+		{"drop", models.MismatchActionDrop, false},
+		{"Drop", models.MismatchActionDrop, false},
+		{"regroup", models.MismatchActionRegroup, false},
+		{"dorp", models.MismatchActionMark, true},
+	}
+	for _, tt := range tests {
+		got, err := parseMismatchAction(tt.action)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("parseMismatchAction(%q) = %s, %v, want %s, error %v", tt.action, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package beautify
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Report 最近一次脚本请求的节点处理统计，用于诊断节点被丢弃的原因
+type Report struct {
+	ConfId  string
+	At      time.Time
+	Total   int // 参与处理的节点数，不含信息节点
+	Kept    int
+	Filter  string
+	Drops   []DropCount
+	Shared  []SharedInfra // 跨订阅共享的服务器或出口
+	Notices []string      // 配置错误等提示
+}
+
+// DropCount 按原因统计的丢弃节点
+type DropCount struct {
+	Reason string
+	Count  int
+	Nodes  []string
+}
+
+const (
+	DropNoDelay     = "no_delay"
+	DropQuarantined = "quarantined"
+	DropMismatch    = "country_mismatch"
+	DropNoCountry   = "no_country"
+)
+
+func (r *Report) drop(reason string, node *ProxieNode) {
+	for i := range r.Drops {
+		if r.Drops[i].Reason == reason {
+			r.Drops[i].Count++
+			r.Drops[i].Nodes = append(r.Drops[i].Nodes, node.Name)
+			return
+		}
+	}
+	r.Drops = append(r.Drops, DropCount{Reason: reason, Count: 1, Nodes: []string{node.Name}})
+}
+
+func (r *Report) notice(err error) {
+	r.Notices = append(r.Notices, err.Error())
+}
+
+var reports sync.Map // confId -> *Report
+
+// GetReports 返回各conf最近一次处理的统计，confId为空时返回全部
+func GetReports(confId string) []*Report {
+	var result []*Report
+	reports.Range(func(k, v any) bool {
+		if confId == "" || k.(string) == confId {
+			result = append(result, v.(*Report))
+		}
+		return true
+	})
+	slices.SortFunc(result, func(a, b *Report) int {
+		return strings.Compare(a.ConfId, b.ConfId)
+	})
+	return result
+}
@@ -0,0 +1,192 @@
+package beautify
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ocyss/sub-store-lab/src/models"
+	"github.com/samber/lo"
+)
+
+// SortStrategy 节点排序策略，分值越小越靠前，分值相同时按延迟排序
+type SortStrategy interface {
+	Name() string
+	Score(node *ProxieNode) float64
+}
+
+// 综合排序时各项归一化的上限
+const (
+	maxSortDelay = 1000.0 // 延迟(ms)
+	maxSortSpeed = 200.0  // 速度(Mbps)
+)
+
+type delayStrategy struct{}
+
+func (delayStrategy) Name() string { return models.SortByDelay }
+
+func (delayStrategy) Score(node *ProxieNode) float64 {
+	return float64(node.Delay)
+}
+
+type speedStrategy struct{}
+
+func (speedStrategy) Name() string { return models.SortBySpeed }
+
+func (speedStrategy) Score(node *ProxieNode) float64 {
+	return -float64(node.Speed.SpeedMbps)
+}
+
+type purityStrategy struct{}
+
+func (purityStrategy) Name() string { return models.SortByPurity }
+
+// Score 未知风险评分排在最后
+func (purityStrategy) Score(node *ProxieNode) float64 {
+	return float64(lo.FromPtrOr(node.Purity.RiskScore, 101))
+}
+
+type stabilityStrategy struct{}
+
+func (stabilityStrategy) Name() string { return models.SortByStability }
+
+func (stabilityStrategy) Score(node *ProxieNode) float64 {
+	return -node.Stability
+}
+
+type costStrategy struct{}
+
+func (costStrategy) Name() string { return models.SortByCost }
+
+// Score 按倍率折算速度，0.5x 倍率的节点速度按两倍计算
+func (costStrategy) Score(node *ProxieNode) float64 {
+	return -float64(node.Speed.SpeedMbps) / rateValue(node.Name)
+}
+
+// compositeStrategy 各项归一化到0-1后按权重加权平均
+type compositeStrategy struct {
+	weights map[string]float64
+}
+
+func (compositeStrategy) Name() string { return models.SortByComposite }
+
+func (s compositeStrategy) Score(node *ProxieNode) float64 {
+	normalized := map[string]float64{
+		models.SortByDelay:     min(float64(node.Delay)/maxSortDelay, 1),
+		models.SortBySpeed:     1 - min(float64(node.Speed.SpeedMbps)/maxSortSpeed, 1),
+		models.SortByPurity:    float64(lo.FromPtrOr(node.Purity.RiskScore, 100)) / 100,
+		models.SortByStability: 1 - node.Stability,
+		models.SortByCost:      1 - min(float64(node.Speed.SpeedMbps)/rateValue(node.Name)/maxSortSpeed, 1),
+	}
+	var score, total float64
+	for name, weight := range s.weights {
+		if weight <= 0 {
+			continue
+		}
+		score += normalized[name] * weight
+		total += weight
+	}
+	if total == 0 {
+		return 0
+	}
+	return score / total
+}
+
+// NewSortStrategy 按配置创建排序策略
+func NewSortStrategy(conf *models.SortConf) (SortStrategy, error) {
+	switch strings.ToLower(conf.Strategy) {
+	case "", models.SortByDelay:
+		return delayStrategy{}, nil
+	case models.SortBySpeed:
+		return speedStrategy{}, nil
+	case models.SortByPurity:
+		return purityStrategy{}, nil
+	case models.SortByStability:
+		return stabilityStrategy{}, nil
+	case models.SortByCost:
+		return costStrategy{}, nil
+	case models.SortByComposite:
+		weights := make(map[string]float64, len(conf.Weights))
+		for name, weight := range conf.Weights {
+			name = strings.ToLower(name)
+			if name == models.SortByComposite {
+				return nil, fmt.Errorf("sort.weights 不支持: %s", name)
+			}
+			if _, err := NewSortStrategy(&models.SortConf{Strategy: name}); err != nil {
+				return nil, fmt.Errorf("sort.weights: %w", err)
+			}
+			weights[name] = weight
+		}
+		return compositeStrategy{weights: weights}, nil
+	default:
+		return nil, fmt.Errorf("未知的排序策略: %s", conf.Strategy)
+	}
+}
+
+// sortNodes 按策略排序节点
+func sortNodes(strategy SortStrategy, nodes []*ProxieNode) {
+	slices.SortStableFunc(nodes, func(a, b *ProxieNode) int {
+		if c := cmp.Compare(strategy.Score(a), strategy.Score(b)); c != 0 {
+			return c
+		}
+		return int(a.Delay) - int(b.Delay)
+	})
+}
+
+// groupScore 国家组分值，只取排序后前3个有延迟的节点计算平均值，避免个别差节点污染整个组
+func groupScore(strategy SortStrategy, nodes []*ProxieNode) float64 {
+	nodes = lo.Filter(nodes, func(node *ProxieNode, _ int) bool {
+		return node.Delay > 0
+	})
+	if len(nodes) == 0 {
+		return math.Inf(1)
+	}
+	sortNodes(strategy, nodes)
+	topN := nodes[:min(3, len(nodes))]
+	return lo.SumBy(topN, strategy.Score) / float64(len(topN))
+}
+
+// orderCountries 固定顺序的国家在前，其余按分值排序
+func orderCountries(countries []string, scores map[string]float64, pinned []string) []string {
+	rank := make(map[string]int, len(pinned))
+	for i, country := range pinned {
+		country = strings.ToUpper(country)
+		if _, ok := rank[country]; !ok {
+			rank[country] = i
+		}
+	}
+	sorted := slices.Clone(countries)
+	slices.SortStableFunc(sorted, func(a, b string) int {
+		ra, aPinned := rank[strings.ToUpper(a)]
+		rb, bPinned := rank[strings.ToUpper(b)]
+		switch {
+		case aPinned && bPinned:
+			return ra - rb
+		case aPinned:
+			return -1
+		case bPinned:
+			return 1
+		}
+		if c := cmp.Compare(scores[a], scores[b]); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+	return sorted
+}
+
+// rateValue 解析节点名称中的倍率，未标注时为1
+func rateValue(name string) float64 {
+	matches := reNodeRate.FindStringSubmatch(name)
+	if len(matches) < 3 {
+		return 1
+	}
+	rate, err := strconv.ParseFloat(lo.CoalesceOrEmpty(matches[1], matches[2]), 64)
+	if err != nil || rate <= 0 {
+		return 1
+	}
+	return rate
+}
@@ -0,0 +1,102 @@
+package beautify
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/ocyss/sub-store-lab/src/models"
+	"github.com/ocyss/sub-store-lab/src/tester"
+	"github.com/samber/lo"
+)
+
+func testNode(name string, delay uint16, mbps int, risk *int, stability float64) *ProxieNode {
+	node := &ProxieNode{
+		Name:      name,
+		Delay:     delay,
+		Speed:     tester.SpeedResult{SpeedMbps: mbps},
+		Stability: stability,
+	}
+	node.Purity.RiskScore = risk
+	return node
+}
+
+func TestSortStrategies(t *testing.T) {
+	nodes := []*ProxieNode{
+		testNode("a", 300, 20, lo.ToPtr(10), 0.9),
+		testNode("b [0.5x]", 100, 40, lo.ToPtr(60), 0.5),
+		testNode("c", 200, 100, nil, 0.7),
+	}
+	tests := []struct {
+		conf models.SortConf
+		want []string
+	}{
+		{conf: models.SortConf{Strategy: "delay"}, want: []string{"b [0.5x]", "c", "a"}},
+		{conf: models.SortConf{Strategy: "speed"}, want: []string{"c", "b [0.5x]", "a"}},
+		{conf: models.SortConf{Strategy: "purity"}, want: []string{"a", "b [0.5x]", "c"}},
+		{conf: models.SortConf{Strategy: "stability"}, want: []string{"a", "c", "b [0.5x]"}},
+		{conf: models.SortConf{Strategy: "cost"}, want: []string{"c", "b [0.5x]", "a"}},
+		{conf: models.SortConf{Strategy: "composite", Weights: map[string]float64{"purity": 1}}, want: []string{"a", "b [0.5x]", "c"}},
+		{conf: models.SortConf{Strategy: "composite", Weights: map[string]float64{"delay": 1, "speed": 0}}, want: []string{"b [0.5x]", "c", "a"}},
+	}
+	for _, tt := range tests {
+		name := tt.conf.Strategy + lo.Ternary(len(tt.conf.Weights) > 0, "-weighted", "")
+		t.Run(name, func(t *testing.T) {
+			strategy, err := NewSortStrategy(&tt.conf)
+			if err != nil {
+				t.Fatalf("NewSortStrategy() error = %v", err)
+			}
+			sorted := slices.Clone(nodes)
+			sortNodes(strategy, sorted)
+			got := lo.Map(sorted, func(n *ProxieNode, _ int) string { return n.Name })
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("sortNodes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSortStrategy_Invalid(t *testing.T) {
+	for _, conf := range []models.SortConf{
+		{Strategy: "random"},
+		{Strategy: "composite", Weights: map[string]float64{"latency": 1}},
+		{Strategy: "composite", Weights: map[string]float64{"composite": 1}},
+	} {
+		if _, err := NewSortStrategy(&conf); err == nil {
+			t.Errorf("NewSortStrategy(%+v) expected error", conf)
+		}
+	}
+}
+
+func Test_orderCountries(t *testing.T) {
+	countries := []string{"US", "JP", "TW", "HK", "SG"}
+	scores := map[string]float64{"US": 50, "JP": 30, "TW": 10, "HK": 40, "SG": 20}
+	tests := []struct {
+		name   string
+		pinned []string
+		want   []string
+	}{
+		{name: "by score", want: []string{"TW", "SG", "JP", "HK", "US"}},
+		{name: "pinned first", pinned: []string{"hk", "US", "KR"}, want: []string{"HK", "US", "TW", "SG", "JP"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := orderCountries(countries, scores, tt.pinned); !slices.Equal(got, tt.want) {
+				t.Errorf("orderCountries() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_groupScore(t *testing.T) {
+	nodes := []*ProxieNode{
+		testNode("a", 100, 0, nil, 0),
+		testNode("b", 200, 0, nil, 0),
+		testNode("c", 0, 0, nil, 0),
+		testNode("d", 300, 0, nil, 0),
+		testNode("e", 900, 0, nil, 0),
+	}
+	// 跳过无延迟节点，取前3个
+	if got := groupScore(delayStrategy{}, nodes); got != 200 {
+		t.Errorf("groupScore() = %v, want 200", got)
+	}
+}
@@ -0,0 +1,71 @@
+package beautify
+
+import (
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/ocyss/sub-store-lab/src/env"
+	"github.com/ocyss/sub-store-lab/src/models"
+)
+
+// subInfoTTL 订阅信息的有效期，订阅从conf中移除后自动过期
+const subInfoTTL = time.Hour * 24 * 30
+
+// subscriptionStates 提取订阅信息后的各订阅状态
+func subscriptionStates(confId string, subs map[string]*Subscription, now time.Time) []models.SubscriptionState {
+	states := make([]models.SubscriptionState, 0, len(subs))
+	for _, sub := range subs {
+		if sub.Info == nil {
+			continue
+		}
+		states = append(states, models.SubscriptionState{
+			ConfId:      confId,
+			SubName:     sub.SubName,
+			Traffic:     sub.Info.Traffic,
+			Remaining:   sub.Info.Remaining,
+			ResetPeriod: sub.Info.ResetPeriod,
+			ExpireDate:  sub.Info.ExpireDate,
+			UpdatedAt:   now,
+		})
+	}
+	return states
+}
+
+func saveSubscriptionStates(states []models.SubscriptionState) {
+	db := env.GetDB()
+	if db == nil || len(states) == 0 {
+		return
+	}
+	err := db.Update(func(txn *badger.Txn) error {
+		for _, state := range states {
+			data, err := json.Marshal(state)
+			if err != nil {
+				return err
+			}
+			key := models.SubInfoKey{ConfId: state.ConfId, SubName: state.SubName}
+			if err := txn.SetEntry(badger.NewEntry(key.ToKey(), data).WithTTL(subInfoTTL)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Warn("failed to save subscription info", "conf", states[0].ConfId, "error", err)
+	}
+}
+
+// GetSubscriptionStates 返回各订阅最近一次提取的信息，confId为空时返回全部
+func GetSubscriptionStates(confId string) ([]models.SubscriptionState, error) {
+	states := make([]models.SubscriptionState, 0)
+	prefix := []byte(models.SubInfoKeyPrefix)
+	if confId != "" {
+		prefix = (&models.SubInfoKey{ConfId: confId}).ToConfPrefixKey()
+	}
+	err := env.QueryDbPrefix(func(_ *badger.Txn, _ []byte, state models.SubscriptionState) error {
+		states = append(states, state)
+		return nil
+	}, prefix, false)
+	return states, err
+}
@@ -1,11 +1,13 @@
 package beautify
 
 import (
+	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/ocyss/sub-store-lab/src/models"
 	"github.com/ocyss/sub-store-lab/src/utils"
 	"github.com/samber/lo"
 )
@@ -14,7 +16,8 @@ type Subscription struct {
 	SubName    string
 	SubNameNum int
 
-	Info *SubscriptionInfo
+	Info     *SubscriptionInfo
+	Userinfo *models.SubscriptionUserinfo // Sub-Store 传入的 subscription-userinfo
 
 	Nodes      []*ProxieNode
 	InfoProxie *ProxieNode
@@ -23,6 +26,7 @@ type Subscription struct {
 // SubscriptionInfo 存储订阅信息
 type SubscriptionInfo struct {
 	Traffic     string        // 剩余流量
+	Remaining   int64         // 剩余流量(字节)，未知为-1
 	ResetPeriod string        // 重置周期
 	ExpireDate  string        // 到期时间
 	InfoProxies []*ProxieNode // 信息节点
@@ -34,47 +38,125 @@ type NodeGroup struct {
 	AvgDelay float64 // 平均延迟
 }
 
-var (
-	reTraffic  = regexp.MustCompile(`剩余流量[:：]\s*([\d.]+)\s*(GB|MB|TB)?`)
-	reReset    = regexp.MustCompile(`(?:距离下次重置剩余|重置).*?[:：]?\s*(\d+)\s*[天日]?`)
-	reExpire   = regexp.MustCompile(`(?:套餐)?到期[:：]?\s*([0-9]{4}-[0-9]{2}-[0-9]{2}|长期有效)`)
-	reNodeRate = regexp.MustCompile(`(?:\[(\d*\.?\d+)x\]|(\d*\.?\d+)x)`)
-	reNotice   = regexp.MustCompile(`(?i)(更新订阅|遇到问题|联系|公告|维护|重启网络|建议|error|错误|尝试|官网|发布页)`)
-)
+var reNodeRate = regexp.MustCompile(`(?:\[(\d*\.?\d+)x\]|(\d*\.?\d+)x)`)
+
+// infoPatterns 编译后的订阅信息识别正则
+type infoPatterns struct {
+	traffic *regexp.Regexp
+	reset   *regexp.Regexp
+	expire  *regexp.Regexp
+	notice  *regexp.Regexp
+}
 
-// 提取订阅信息
-func (s *Subscription) ExtractInfoNode() map[string]any {
-	info := &SubscriptionInfo{}
+var defaultInfoPatterns = lo.Must(newInfoPatterns(models.DefaultInfoPatterns()))
+
+// newInfoPatterns 编译订阅信息识别正则，为空的项使用默认值
+func newInfoPatterns(conf models.InfoPatterns) (*infoPatterns, error) {
+	defaults := models.DefaultInfoPatterns()
+	compile := func(name, expr, fallback string, groups int) (*regexp.Regexp, error) {
+		re, err := regexp.Compile(lo.CoalesceOrEmpty(expr, fallback))
+		if err != nil {
+			return nil, fmt.Errorf("info_patterns.%s: %w", name, err)
+		}
+		if re.NumSubexp() < groups {
+			return nil, fmt.Errorf("info_patterns.%s: 至少需要%d个捕获分组", name, groups)
+		}
+		return re, nil
+	}
+	var (
+		p   infoPatterns
+		err error
+	)
+	if p.traffic, err = compile("traffic", conf.Traffic, defaults.Traffic, 1); err != nil {
+		return nil, err
+	}
+	if p.reset, err = compile("reset", conf.Reset, defaults.Reset, 1); err != nil {
+		return nil, err
+	}
+	if p.expire, err = compile("expire", conf.Expire, defaults.Expire, 1); err != nil {
+		return nil, err
+	}
+	if p.notice, err = compile("notice", conf.Notice, defaults.Notice, 0); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// 提取订阅信息，有 subscription-userinfo 时优先使用其中的流量及到期时间
+func (s *Subscription) ExtractInfoNode(patterns *infoPatterns) map[string]any {
+	info := &SubscriptionInfo{Remaining: -1}
 
 	groupSub := lo.GroupBy(s.Nodes, func(p *ProxieNode) bool {
 		line := p.Name
 		switch {
-		case reTraffic.MatchString(line):
-			m := reTraffic.FindStringSubmatch(line)
-			info.Traffic = m[1] + " " + m[2]
+		case patterns.traffic.MatchString(line):
+			m := patterns.traffic.FindStringSubmatch(line)
+			info.Traffic = m[1] + " " + normalizeTrafficUnit(lo.NthOr(m, 2, ""))
+			if remaining, err := utils.ParseBytes(info.Traffic); err == nil {
+				info.Remaining = remaining
+			}
 			return true
-		case reReset.MatchString(line):
-			m := reReset.FindStringSubmatch(line)
+		case patterns.reset.MatchString(line):
+			m := patterns.reset.FindStringSubmatch(line)
 			info.ResetPeriod = m[1] + "天"
 			return true
-		case reExpire.MatchString(line):
-			m := reExpire.FindStringSubmatch(line)
-			info.ExpireDate = m[1]
+		case patterns.expire.MatchString(line):
+			m := patterns.expire.FindStringSubmatch(line)
+			info.ExpireDate = normalizeExpireDate(m[1])
 			return true
-		case reNotice.MatchString(line):
+		case patterns.notice.MatchString(line):
 			return true
 		default:
 			return false
 		}
 	})
 
+	if u := s.Userinfo; u != nil {
+		if remaining := u.Remaining(); remaining >= 0 {
+			info.Traffic = utils.HumanBytes(remaining)
+			info.Remaining = remaining
+		}
+		if expire := u.ExpireTime(); !expire.IsZero() {
+			info.ExpireDate = expire.Format(time.DateOnly)
+		}
+	}
+
 	info.InfoProxies = groupSub[true]
 	s.Nodes = groupSub[false]
 	s.Info = info
 
+	if len(s.Nodes) == 0 {
+		return nil
+	}
 	return s.formatInfoNode()
 }
 
+// normalizeTrafficUnit 统一流量单位为 GB/MB/TB，未标注时为GB
+func normalizeTrafficUnit(unit string) string {
+	unit = strings.ToUpper(unit)
+	if unit == "" {
+		return "GB"
+	}
+	if !strings.HasSuffix(unit, "B") {
+		unit += "B"
+	}
+	return unit
+}
+
+// normalizeExpireDate 统一到期日期为 2006-01-02 格式，长期有效的各种写法统一为"长期有效"
+func normalizeExpireDate(date string) string {
+	switch strings.ToLower(date) {
+	case "never", "permanent", "unlimited":
+		return "长期有效"
+	}
+	for _, layout := range []string{"2006-1-2", "2006/1/2", "2006.1.2"} {
+		if t, err := time.Parse(layout, date); err == nil {
+			return t.Format(time.DateOnly)
+		}
+	}
+	return date
+}
+
 // 格式化订阅信息为单信息节点
 func (s *Subscription) formatInfoNode() map[string]any {
 	info := s.Info
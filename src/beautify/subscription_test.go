@@ -0,0 +1,85 @@
+package beautify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ocyss/sub-store-lab/src/models"
+)
+
+func TestSubscription_ExtractInfoNode(t *testing.T) {
+	tests := []struct {
+		name     string
+		names    []string
+		userinfo *models.SubscriptionUserinfo
+		want     SubscriptionInfo
+		wantLeft int
+	}{
+		{
+			name:     "chinese",
+			names:    []string{"剩余流量：123.45 GB", "距离下次重置剩余：12 天", "套餐到期：2025-12-31", "官网 example.com", "🇭🇰 香港 01"},
+			want:     SubscriptionInfo{Traffic: "123.45 GB", ResetPeriod: "12天", ExpireDate: "2025-12-31"},
+			wantLeft: 1,
+		},
+		{
+			name:     "english",
+			names:    []string{"Traffic Left: 50.5G", "Reset in 7 days", "Expire Date: 2026/1/5", "Official website: example.com", "HK 01", "JP 01"},
+			want:     SubscriptionInfo{Traffic: "50.5 GB", ResetPeriod: "7天", ExpireDate: "2026-01-05"},
+			wantLeft: 2,
+		},
+		{
+			name:     "never expires",
+			names:    []string{"Expires: Never", "US 01"},
+			want:     SubscriptionInfo{ExpireDate: "长期有效"},
+			wantLeft: 1,
+		},
+		{
+			name:  "userinfo preferred",
+			names: []string{"剩余流量：1 GB", "套餐到期：2025-12-31", "SG 01"},
+			userinfo: &models.SubscriptionUserinfo{
+				Upload:   1 << 30,
+				Download: 2 << 30,
+				Total:    100 << 30,
+				Expire:   time.Date(2030, 1, 1, 12, 0, 0, 0, time.Local).Unix(),
+			},
+			want:     SubscriptionInfo{Traffic: "97GB", ExpireDate: "2030-01-01"},
+			wantLeft: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := &Subscription{SubName: "A", Userinfo: tt.userinfo}
+			for _, name := range tt.names {
+				sub.AddNode(map[string]any{"name": name, "type": "ss"})
+			}
+			node := sub.ExtractInfoNode(defaultInfoPatterns)
+			if node == nil {
+				t.Fatal("ExtractInfoNode() = nil")
+			}
+			got := sub.Info
+			if got.Traffic != tt.want.Traffic || got.ResetPeriod != tt.want.ResetPeriod || got.ExpireDate != tt.want.ExpireDate {
+				t.Errorf("Info = {%q %q %q}, want {%q %q %q}",
+					got.Traffic, got.ResetPeriod, got.ExpireDate, tt.want.Traffic, tt.want.ResetPeriod, tt.want.ExpireDate)
+			}
+			if len(sub.Nodes) != tt.wantLeft {
+				t.Errorf("len(Nodes) = %d, want %d", len(sub.Nodes), tt.wantLeft)
+			}
+		})
+	}
+}
+
+func Test_newInfoPatterns(t *testing.T) {
+	if _, err := newInfoPatterns(models.InfoPatterns{Traffic: `剩余[`}); err == nil {
+		t.Error("newInfoPatterns() with invalid regexp should fail")
+	}
+	if _, err := newInfoPatterns(models.InfoPatterns{Reset: `重置\d+`}); err == nil {
+		t.Error("newInfoPatterns() without capture group should fail")
+	}
+	p, err := newInfoPatterns(models.InfoPatterns{Traffic: `流量余额\s*(\d+)(G)`})
+	if err != nil {
+		t.Fatalf("newInfoPatterns() error = %v", err)
+	}
+	if !p.traffic.MatchString("流量余额 30G") || !p.expire.MatchString("套餐到期：2025-12-31") {
+		t.Error("custom pattern should replace only the configured item")
+	}
+}
@@ -0,0 +1,142 @@
+package beautify
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/samber/lo"
+)
+
+// DefaultNameTemplate 默认节点名称格式: [旗帜]国家_序号🏠速率[关键词][0.5x]🩵订阅名
+const DefaultNameTemplate = `{{.Flag}}{{.Country}}{{.Mismatch}}_{{.Index}}{{.TypeIcon}}{{.Speed}}` +
+	`{{with .Keywords}}[{{join . "|"}}]{{end}}{{.Rate}}{{.PurityIcon}}{{.Sub}}`
+
+// NodeFacts 节点名称模板可用的字段，流媒体解锁结果需等待解锁测试器，暂未提供
+type NodeFacts struct {
+	Flag           string   // 国旗
+	Country        string   // 国家代码，regroup 时为声明的国家
+	Mismatch       string   // 国家不一致标记
+	ClaimedCountry string   // 名称中声明的国家
+	Index          int      // 国家组内序号，按节点身份持久化，刷新后不变
+	Delay          int      // 延迟(ms)
+	Speed          string   // 下载速度，如 2.1MB，未测速为 -1KB
+	SpeedMbps      int      // 下载速度(Mbps)
+	RiskScore      int      // 风险评分，未知为 -1
+	PurityIcon     string   // 纯净度图标
+	TypeIcon       string   // 使用类型图标
+	UsageType      string   // 使用类型
+	IP             string   // 出口IP
+	City           string   // 城市
+	Region         string   // 地区
+	ASN            int      // 自治系统号
+	Org            string   // ASN所属组织
+	Rate           string   // 倍率，如 [0.5x]，1倍为空
+	Keywords       []string // 名称中命中的保留关键词
+	Sub            string   // 订阅名
+	OldName        string   // 原始名称
+	Stale          bool     // 使用了过期的测试结果
+}
+
+var nameTemplateFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+var defaultNameTemplate = template.Must(template.New("name").Funcs(nameTemplateFuncs).Parse(DefaultNameTemplate))
+
+// sampleFacts 用于校验模板的示例节点
+var sampleFacts = &NodeFacts{
+	Flag: "🇭🇰", Country: "HK", Index: 1, Delay: 120, Speed: "2.1MB", SpeedMbps: 17, RiskScore: 10,
+	PurityIcon: "🩵", TypeIcon: "🏠", UsageType: "Residential", IP: "203.0.113.1", City: "Hong Kong",
+	Region: "Hong Kong", ASN: 9269, Org: "HKBN", Rate: "[0.5x]", Keywords: []string{"家宽"}, Sub: "sub",
+	OldName: "🇭🇰 香港 01",
+}
+
+// nameTemplate 节点名称模板，执行失败的节点使用默认格式并记录首个错误
+type nameTemplate struct {
+	tmpl *template.Template
+	err  error
+}
+
+// newNameTemplate 解析并校验模板，为空时使用默认格式
+func newNameTemplate(text string) (*nameTemplate, error) {
+	if strings.TrimSpace(text) == "" {
+		return &nameTemplate{tmpl: defaultNameTemplate}, nil
+	}
+	tmpl, err := template.New("name").Funcs(nameTemplateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("解析name_template失败: %w", err)
+	}
+	t := &nameTemplate{tmpl: tmpl}
+	if _, err := t.render(sampleFacts); err != nil {
+		return nil, fmt.Errorf("校验name_template失败: %w", err)
+	}
+	return t, nil
+}
+
+func (t *nameTemplate) render(facts *NodeFacts) (string, error) {
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, facts); err != nil {
+		return "", err
+	}
+	name := strings.TrimSpace(buf.String())
+	if name == "" {
+		return "", fmt.Errorf("生成的名称为空")
+	}
+	return name, nil
+}
+
+// Execute 生成节点名称
+func (t *nameTemplate) Execute(facts *NodeFacts) string {
+	name, err := t.render(facts)
+	if err == nil {
+		return name
+	}
+	if t.err == nil {
+		t.err = fmt.Errorf("执行name_template失败: %w", err)
+	}
+	var buf bytes.Buffer
+	_ = defaultNameTemplate.Execute(&buf, facts)
+	return buf.String()
+}
+
+// uniqueNames 为重复的节点名称添加序号后缀，模板未使用 .Index 等区分字段时不同节点可能生成相同名称
+type uniqueNames struct {
+	used       map[string]bool
+	duplicates []string
+}
+
+// apply 名称已被使用时改为 名称_2、名称_3 ...
+func (u *uniqueNames) apply(proxie map[string]any) {
+	name, ok := proxie["name"].(string)
+	if !ok {
+		return
+	}
+	if u.used == nil {
+		u.used = make(map[string]bool)
+	}
+	if u.used[name] {
+		unique := name
+		for i := 2; u.used[unique]; i++ {
+			unique = fmt.Sprintf("%s_%d", name, i)
+		}
+		if !slices.Contains(u.duplicates, name) {
+			u.duplicates = append(u.duplicates, name)
+		}
+		proxie["name"] = unique
+		name = unique
+	}
+	u.used[name] = true
+}
+
+// err 存在重复名称时返回提示
+func (u *uniqueNames) err() error {
+	if len(u.duplicates) == 0 {
+		return nil
+	}
+	return fmt.Errorf("name_template生成了%d个重复名称, 已添加序号后缀: %s", len(u.duplicates), strings.Join(lo.Slice(u.duplicates, 0, 3), ", "))
+}
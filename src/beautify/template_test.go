@@ -0,0 +1,85 @@
+package beautify
+
+import "testing"
+
+func Test_nameTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "default",
+			text: "",
+			want: "🇭🇰HK_1🏠2.1MB[家宽][0.5x]🩵sub",
+		},
+		{
+			name: "custom fields",
+			text: `{{.Flag}}{{.Country}}_{{.Index}} {{.City}} AS{{.ASN}} {{.Delay}}ms{{if ge .RiskScore 0}} {{.RiskScore}}{{end}}`,
+			want: "🇭🇰HK_1 Hong Kong AS9269 120ms 10",
+		},
+		{
+			name: "funcs",
+			text: `{{lower .Country}}-{{.Index}}|{{join .Keywords ","}}`,
+			want: "hk-1|家宽",
+		},
+		{
+			name:    "syntax error",
+			text:    `{{.Country`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown field",
+			text:    `{{.Nonexistent}}`,
+			wantErr: true,
+		},
+		{
+			name:    "empty result",
+			text:    `{{if false}}x{{end}}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := newNameTemplate(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newNameTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := tmpl.Execute(sampleFacts); got != tt.want {
+				t.Errorf("Execute() = %q, want %q", got, tt.want)
+			}
+			if tmpl.err != nil {
+				t.Errorf("Execute() recorded error %v", tmpl.err)
+			}
+		})
+	}
+}
+
+func Test_uniqueNames(t *testing.T) {
+	// 模板 {{.Flag}}{{.Country}} 未使用序号，同国家节点名称相同
+	names := []string{"🇭🇰HK", "🇭🇰HK", "🇯🇵JP", "🇭🇰HK", "🇭🇰HK_2"}
+	want := []string{"🇭🇰HK", "🇭🇰HK_2", "🇯🇵JP", "🇭🇰HK_3", "🇭🇰HK_2_2"}
+
+	var u uniqueNames
+	for i, name := range names {
+		proxie := map[string]any{"name": name}
+		u.apply(proxie)
+		if proxie["name"] != want[i] {
+			t.Errorf("apply(%q) = %q, want %q", name, proxie["name"], want[i])
+		}
+	}
+	if err := u.err(); err == nil {
+		t.Error("err() = nil, want duplicate notice")
+	}
+
+	var none uniqueNames
+	none.apply(map[string]any{"name": "a"})
+	none.apply(map[string]any{"name": "b"})
+	if err := none.err(); err != nil {
+		t.Errorf("err() = %v, want nil", err)
+	}
+}
@@ -48,6 +48,13 @@ func initDB() {
 	}
 }
 
+// InitMemoryDB 使用内存数据库，用于测试
+func InitMemoryDB() error {
+	var err error
+	db, err = badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(dbLogger{}))
+	return err
+}
+
 func GetDB() *badger.DB {
 	return db
 }
@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/caarlos0/env/v11"
 	"github.com/joho/godotenv"
@@ -13,6 +14,9 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// Prefix 环境变量前缀
+const Prefix = "LAB_"
+
 var (
 	Conf         envConfig
 	OverrideYaml map[string]any
@@ -35,12 +39,34 @@ type envConfig struct {
 
 	DelayTestUrl string `env:"DELAY_TEST_URL" envDefault:"https://www.gstatic.com/generate_204"`
 
+	Timezone string `env:"TIMEZONE"` // 全局cron时区，如 Asia/Shanghai，为空使用容器本地时区，conf中的timezone优先
+
+	IPCacheTTL time.Duration `env:"IP_CACHE_TTL" envDefault:"24h"` // 按出口IP缓存检测结果的时长，跨节点及conf共享，部分检测器失败时最多缓存1小时，0为不缓存
+
 	DisableTester string `env:"DISABLE_TESTER"` // 逗号分割, 不区分大小写，默认不禁用: Purity,Speed
 
 	IpQualityAPIKey  string `env:"IPQUALITY_API_KEY"`  // https://www.ipqualityscore.com/create-account
 	AbuseIPDBAPIKey  string `env:"ABUSEIPDB_API_KEY"`  // https://www.abuseipdb.com/account
 	IpregistryAPIKey string `env:"IPREGISTRY_API_KEY"` // https://dashboard.ipregistry.co/apikeys
 	IpDataAPIKey     string `env:"IPDATA_API_KEY"`     // https://dashboard.ipdata.co/api.html
+
+	ProxyCheckAPIKey  string `env:"PROXYCHECK_API_KEY"`                                  // https://proxycheck.io/dashboard/
+	ScamalyticsAPIKey string `env:"SCAMALYTICS_API_KEY"`                                 // https://scamalytics.com/ip/api/enquiry 格式: 用户名:密钥
+	ScamalyticsHost   string `env:"SCAMALYTICS_HOST" envDefault:"api11.scamalytics.com"` // 开通后邮件中分配的接口域名
+	IpApiIsAPIKey     string `env:"IPAPIIS_API_KEY"`                                     // https://ipapi.is/app/dashboard
+	IP2LocationAPIKey string `env:"IP2LOCATION_API_KEY"`                                 // https://www.ip2location.io/dashboard
+
+	// 本地离线IP数据库，相对路径基于DATA_DIR，文件存在时启用，修改后自动重新加载
+	MMDBCity string `env:"MMDB_CITY" envDefault:"GeoLite2-City.mmdb"` // MaxMind/DB-IP City 格式
+	MMDBASN  string `env:"MMDB_ASN" envDefault:"GeoLite2-ASN.mmdb"`   // MaxMind/DB-IP ASN 格式
+	ASNUsage string `env:"ASN_USAGE" envDefault:"asn-usage.txt"`      // ASN→使用类型列表，每行: AS13335 Datacenter
+
+	PurityRules      string `env:"PURITY_RULES" envDefault:"purity-rules.yaml"`   // 自定义ASN/网段/组织分类规则，相对路径基于DATA_DIR，修改后自动重新加载
+	GenericDetectors string `env:"GENERIC_DETECTORS" envDefault:"detectors.yaml"` // yaml声明的通用检测器，相对路径基于DATA_DIR，修改后自动重新加载
+
+	// 检测器录制/回放: record 录制接口响应，replay 仅使用录制的响应，不访问网络，为空正常请求
+	DetectorMode      string `env:"DETECTOR_MODE"`
+	DetectorReplayDir string `env:"DETECTOR_REPLAY_DIR" envDefault:"replay"` // 录制文件目录，相对路径基于DATA_DIR
 }
 
 func init() {
@@ -57,7 +83,7 @@ func init() {
 	// 	slog.Warn("failed to load env", "error", err, "workdir", dir)
 	// }
 	err = env.ParseWithOptions(&Conf, env.Options{
-		Prefix:                "LAB_",
+		Prefix:                Prefix,
 		UseFieldNameByDefault: true,
 	})
 	if err != nil {
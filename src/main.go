@@ -22,6 +22,9 @@ import (
 
 func main() {
 	env.InitService()
+	if err := tester.MigrateProxieKeys(); err != nil {
+		slog.Error("tester.MigrateProxieKeys", "error", err)
+	}
 	tester.InitCron()
 
 	if env.Conf.EnableMihomoDNS == "true" {
@@ -55,6 +58,14 @@ func main() {
 		})
 
 		r.POST("/", ScriptHandler)
+
+		api := r.Group("/api")
+		{
+			api.GET("/exits", ExitsHandler)
+			api.GET("/quota", QuotaHandler)
+			api.GET("/report", ReportHandler)
+			api.GET("/subscriptions", SubscriptionsHandler)
+		}
 	}
 	addr := fmt.Sprintf("%s:%d", env.Conf.Host, env.Conf.Port)
 	slog.Info("Server listening on", "address", addr)
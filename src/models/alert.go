@@ -0,0 +1,25 @@
+package models
+
+const (
+	AlertFormatJSON     = "json"     // 通用JSON，POST告警内容
+	AlertFormatTelegram = "telegram" // Telegram Bot sendMessage，webhook为 https://api.telegram.org/bot<token>/sendMessage
+)
+
+// AlertConf 订阅到期及流量告警配置，Webhook为空时不告警
+type AlertConf struct {
+	Webhook    string `json:"webhook"`     // 告警地址
+	Format     string `json:"format"`      // 请求格式: json/telegram，默认:json
+	ChatId     string `json:"chat_id"`     // telegram 的 chat_id
+	ExpireDays int    `json:"expire_days"` // 距离到期不超过该天数时告警，0为不检查，默认:3
+	MinTraffic string `json:"min_traffic"` // 剩余流量低于该值时告警，如 10GB，为空不检查，默认:10GB
+	Repeat     string `json:"repeat"`      // 同一订阅的同类告警重复发送的最小间隔，默认:1d
+}
+
+func DefaultAlertConf() AlertConf {
+	return AlertConf{
+		Format:     AlertFormatJSON,
+		ExpireDays: 3,
+		MinTraffic: "10GB",
+		Repeat:     "1d",
+	}
+}
@@ -3,6 +3,7 @@ package models
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 )
 
 type Args struct {
@@ -33,15 +34,25 @@ type Args struct {
 		Feature struct{} `json:"feature"`
 		// Meta    struct {} `json:"meta"`
 	} `json:"context"`
+
+	SubSources map[string]SubSource `json:"-"` // context.source 中的各订阅，键为订阅名
+}
+
+// SubSource Sub-Store 传入的单个订阅信息
+type SubSource struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+	SubUserinfo string `json:"subUserinfo"` // upload=xx; download=xx; total=xx; expire=xx
 }
 
 func (a *Args) GetProxieInfo(proxie map[string]any) *ProxieInfo {
 	return &ProxieInfo{
 		Id: ProxieKey{
-			ConfId:     a.Conf.Id,
-			SubName:    proxie["_subName"].(string),
-			ProxieName: proxie["name"].(string),
+			ConfId:      a.Conf.Id,
+			SubName:     proxie["_subName"].(string),
+			Fingerprint: ProxieFingerprint(proxie),
 		},
+		Name: proxie["name"].(string),
 		Conf: &a.Conf,
 	}
 }
@@ -77,7 +88,30 @@ func (a *Args) UnmarshalJSON(data []byte) error {
 		}
 		if len(rawArgs) >= 3 {
 			_ = json.Unmarshal(rawArgs[2], &a.Context)
+			a.SubSources = parseSubSources(rawArgs[2])
 		}
 	}
 	return nil
 }
+
+// parseSubSources 解析 context.source 中除 _collection 外的订阅
+func parseSubSources(data json.RawMessage) map[string]SubSource {
+	var ctx struct {
+		Source map[string]json.RawMessage `json:"source"`
+	}
+	if err := json.Unmarshal(data, &ctx); err != nil {
+		return nil
+	}
+	sources := make(map[string]SubSource, len(ctx.Source))
+	for name, raw := range ctx.Source {
+		if strings.HasPrefix(name, "_") {
+			continue
+		}
+		var src SubSource
+		if err := json.Unmarshal(raw, &src); err != nil {
+			continue
+		}
+		sources[name] = src
+	}
+	return sources
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"log/slog"
 	"strings"
@@ -12,25 +14,78 @@ type Conf struct {
 	PurityCron string `json:"purity_cron"` // 纯净度测试 cron表达式
 	SpeedCron  string `json:"speed_cron"`  // 速度/延迟测试 cron表达式
 
+	Timezone   string `json:"timezone"`    // cron表达式时区，如 Asia/Shanghai，为空使用全局配置
+	QuietHours string `json:"quiet_hours"` // 静默时段，逗号分割，如 19:00-23:30，期间推迟测速等重任务
+	Jitter     int    `json:"jitter"`      // 定时任务随机延后启动的最大秒数，避免多个conf同时启动，默认:120
+
 	SpeedTestUrl string `json:"speed_test_url"` // 测速测试URL
 
 	MinSpeed        int `json:"min_speed"`        // 最低测速结果(KB/s)，低于此值舍弃，默认:256
 	DownloadTimeout int `json:"download_timeout"` // 下载测试时间(秒)，与下载链接大小相关。默认:8
 	DownloadMB      int `json:"download_mb"`      // 单节点测速下载数据大小(MB)限制，0为不限，默认:20
 
+	ResultTTL map[string]string `json:"result_ttl"` // 各测试器结果有效期，如 {"purity":"3d","speed":"26h"}，默认由cron间隔推导
+	StaleTTL  string            `json:"stale_ttl"`  // 结果过期后仍保留并标记为stale的时长，默认为一个cron间隔
+
+	RetryTimes      int  `json:"retry_times"`      // 单次运行中临时性失败的重试次数，默认:2
+	QuarantineAfter int  `json:"quarantine_after"` // 连续失败多少次后隔离节点，隔离节点降低测试频率，0为不隔离，默认:3
+	DropQuarantined bool `json:"drop_quarantined"` // 脚本返回时丢弃隔离中的节点
+
+	MaxPerCountry      int `json:"max_per_country"`      // 每个国家最多保留的节点数，按排序选择，0为不限
+	MaxPerSubscription int `json:"max_per_subscription"` // 每个国家内每个订阅最多保留的节点数，0为不限
+	MaxTotal           int `json:"max_total"`            // 最多保留的节点总数，0为不限
+
+	Dedup DedupConf `json:"dedup"` // 跨订阅重复节点去重配置
+
+	MismatchAction string `json:"mismatch_action"` // 节点名称声明的国家与检测结果不一致时: mark 标记，drop 丢弃，regroup 按声明的国家分组，默认:mark
+
+	Scoring ScoringProfile `json:"scoring"` // 纯净度合并评分配置
+
+	Sort SortConf `json:"sort"` // 国家组及节点排序配置
+
 	KeywordKeep string `json:"keyword_keep"` // 关键词保留，| 竖线分割
 
+	InfoPatterns InfoPatterns `json:"info_patterns"` // 订阅信息节点(剩余流量/重置/到期/公告)的识别正则
+	Alert        AlertConf    `json:"alert"`         // 订阅到期及流量不足时通过webhook告警
+
+	NameTemplate string `json:"name_template"` // 节点名称模板(text/template)，为空使用默认格式，生成的名称重复时添加序号后缀
+	Filter       string `json:"filter"`        // 节点过滤表达式，结果为假的节点被丢弃，为空不过滤
+
+	ProxyGroups bool `json:"proxy_groups"` // 返回 {proxies, proxy-groups}，策略组按 override.yaml 的 lab-groups 生成
+
 	PurityIconStr string `json:"purity_icon"`
 	TypeIconStr   string `json:"type_icon"`
+	UncertainIcon string `json:"uncertain_icon"` // 检测器分歧较大时替代纯净度图标，为空不替代
 
 	PurityIcon []string `json:"-"`
 	TypeIcon   []string `json:"-"`
 }
 
 func (c *Conf) Eq(other *Conf) bool {
-	return c.Id == other.Id && c.SpeedTestUrl == other.SpeedTestUrl
+	return c.Id == other.Id && c.Hash() == other.Hash()
+}
+
+// Hash 返回完整conf的摘要，任意字段变化都会导致摘要变化
+func (c *Conf) Hash() string {
+	return HashOf(c)
+}
+
+// HashOf 返回任意值json序列化后的摘要
+func HashOf(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
 }
 
+const (
+	MismatchActionMark    = "mark"
+	MismatchActionDrop    = "drop"
+	MismatchActionRegroup = "regroup"
+)
+
 var (
 	PurityIconStr = "🖤|🩵|💙|💛|🧡|❤️"
 	TypeIconStr   = "🪨|🏠|🕋"
@@ -42,6 +97,7 @@ func DefaultConf() *Conf {
 	return &Conf{
 		PurityCron: "0 2 */3 * *", // 每3天的2点执行一次纯净度测试
 		SpeedCron:  "0 3 * * *",   // 每天3点执行一次延迟测试
+		Jitter:     120,
 
 		// 默认测速URL
 		SpeedTestUrl: "https://github.com/comfyanonymous/ComfyUI/releases/download/v0.3.57/ComfyUI_windows_portable_nvidia.7z",
@@ -50,6 +106,20 @@ func DefaultConf() *Conf {
 		DownloadTimeout: 8,
 		DownloadMB:      20,
 
+		RetryTimes:      2,
+		QuarantineAfter: 3,
+
+		Dedup: DefaultDedupConf(),
+
+		MismatchAction: MismatchActionMark,
+
+		Scoring: DefaultScoringProfile(),
+
+		Sort: DefaultSortConf(),
+
+		InfoPatterns: DefaultInfoPatterns(),
+		Alert:        DefaultAlertConf(),
+
 		PurityIconStr: PurityIconStr,
 		TypeIconStr:   TypeIconStr,
 		PurityIcon:    PurityIcon,
@@ -0,0 +1,24 @@
+package models
+
+const (
+	DedupByFingerprint = "fingerprint" // 连接参数(服务器/端口/凭据等)相同
+	DedupByExitIP      = "exit_ip"     // 检测到的出口IP相同
+)
+
+const (
+	DedupKeepBest     = "best"     // 按排序策略保留最优的副本
+	DedupKeepPriority = "priority" // 保留订阅顺序靠前的副本
+	DedupKeepCost     = "cost"     // 保留倍率最低的副本，相同时按排序策略
+)
+
+// DedupConf 跨订阅重复节点的检测配置，By为空时不去重
+type DedupConf struct {
+	By   []string `json:"by"`   // 判断重复的依据: fingerprint/exit_ip，按顺序依次去重
+	Keep string   `json:"keep"` // 保留规则: best/priority/cost，默认:best
+}
+
+func DefaultDedupConf() DedupConf {
+	return DedupConf{
+		Keep: DedupKeepBest,
+	}
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+)
+
+// ProxieFingerprint 由连接参数生成的节点指纹，与名称、序号及 Sub-Store 附加的 _ 开头字段无关
+func ProxieFingerprint(proxie map[string]any) string {
+	params := make(map[string]any, len(proxie))
+	for k, v := range proxie {
+		if k == "name" || k == "id" || strings.HasPrefix(k, "_") || v == nil {
+			continue
+		}
+		params[k] = v
+	}
+	// map按key排序序列化，结果与字段顺序无关
+	data, _ := json.Marshal(params)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,42 @@
+package models
+
+import "testing"
+
+func TestProxieFingerprint(t *testing.T) {
+	base := func() map[string]any {
+		return map[string]any{
+			"name":     "🇭🇰 香港01",
+			"id":       35,
+			"type":     "vmess",
+			"server":   "hk.example.com",
+			"port":     443,
+			"uuid":     "f769b579-b9a8-4a0d-b86a-d0df01522150",
+			"ws-opts":  map[string]any{"path": "/ws", "headers": map[string]any{"Host": "cdn.example.com"}},
+			"_subName": "A",
+		}
+	}
+	want := ProxieFingerprint(base())
+
+	tests := []struct {
+		name   string
+		modify func(p map[string]any)
+		same   bool
+	}{
+		{"renamed", func(p map[string]any) { p["name"] = "HK-01" }, true},
+		{"reordered", func(p map[string]any) { p["id"] = 1 }, true},
+		{"sub-store fields", func(p map[string]any) { p["_subDisplayName"] = "B"; p["_lab_old_name"] = "x" }, true},
+		{"nil value", func(p map[string]any) { p["servername"] = nil }, true},
+		{"credentials", func(p map[string]any) { p["uuid"] = "00000000-0000-0000-0000-000000000000" }, false},
+		{"port", func(p map[string]any) { p["port"] = 8443 }, false},
+		{"transport", func(p map[string]any) { p["ws-opts"] = map[string]any{"path": "/other"} }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := base()
+			tt.modify(p)
+			if got := ProxieFingerprint(p); (got == want) != tt.same {
+				t.Errorf("ProxieFingerprint() = %s, base %s, want same %v", got, want, tt.same)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// InfoPatterns 订阅信息节点的识别正则，未配置的项使用默认值
+type InfoPatterns struct {
+	Traffic string `json:"traffic"` // 剩余流量，分组1为数值，分组2为单位(可选)
+	Reset   string `json:"reset"`   // 距离重置的天数，分组1为天数
+	Expire  string `json:"expire"`  // 到期日期，分组1为日期或长期有效
+	Notice  string `json:"notice"`  // 公告等其他信息节点，仅识别不提取
+}
+
+func DefaultInfoPatterns() InfoPatterns {
+	return InfoPatterns{
+		Traffic: `(?i)(?:剩余流量|traffic\s*(?:left|remaining)|remaining\s*(?:traffic|data)|data\s*left)\s*[:：]?\s*([\d.]+)\s*([GMT]B?)?`,
+		Reset:   `(?i)(?:距离下次重置剩余|重置|resets?\s*(?:in|after)?).*?[:：]?\s*(\d+)\s*(?:[天日]|days?|d\b)?`,
+		Expire:  `(?i)(?:(?:套餐)?到期|expir(?:es|ed|e|y)(?:\s*(?:at|on|date))?)\s*[:：]?\s*([0-9]{4}[-/.][0-9]{1,2}[-/.][0-9]{1,2}|长期有效|never|permanent|unlimited)`,
+		Notice:  `(?i)(更新订阅|遇到问题|联系|公告|维护|重启网络|建议|error|错误|尝试|官网|发布页|website|official|contact|notice|announcement|maintenance|update\s*subscription)`,
+	}
+}
+
+// SubscriptionState 每次脚本请求提取的订阅信息，按conf及订阅保存
+type SubscriptionState struct {
+	ConfId      string
+	SubName     string
+	Traffic     string // 剩余流量，如 123.45 GB
+	Remaining   int64  // 剩余流量(字节)，未知为-1
+	ResetPeriod string // 距离重置的天数，如 12天
+	ExpireDate  string // 到期日期 2006-01-02 或 长期有效
+	UpdatedAt   time.Time
+}
+
+// SubscriptionUserinfo 订阅的 subscription-userinfo 数据，单位为字节，Expire为0表示不过期
+type SubscriptionUserinfo struct {
+	Upload   int64
+	Download int64
+	Total    int64
+	Expire   int64 // unix时间戳(秒)
+}
+
+// Remaining 剩余流量(字节)，总量未知时返回-1
+func (u *SubscriptionUserinfo) Remaining() int64 {
+	if u.Total <= 0 {
+		return -1
+	}
+	return max(u.Total-u.Upload-u.Download, 0)
+}
+
+// ExpireTime 到期时间，不过期时返回零值
+func (u *SubscriptionUserinfo) ExpireTime() time.Time {
+	if u.Expire <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(u.Expire, 0)
+}
+
+// ParseSubscriptionUserinfo 解析 upload=xx; download=xx; total=xx; expire=xx 格式的订阅信息
+// 不包含 total 和 expire 时返回nil
+func ParseSubscriptionUserinfo(s string) *SubscriptionUserinfo {
+	info := &SubscriptionUserinfo{}
+	found := false
+	for part := range strings.SplitSeq(s, ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		// 部分机场使用浮点数
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			continue
+		}
+		n := int64(f)
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "upload":
+			info.Upload = n
+		case "download":
+			info.Download = n
+		case "total":
+			info.Total = n
+			found = true
+		case "expire":
+			info.Expire = n
+			found = true
+		}
+	}
+	if !found {
+		return nil
+	}
+	return info
+}
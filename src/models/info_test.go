@@ -0,0 +1,58 @@
+package models
+
+import "testing"
+
+func TestParseSubscriptionUserinfo(t *testing.T) {
+	tests := []struct {
+		name          string
+		s             string
+		want          *SubscriptionUserinfo
+		wantRemaining int64
+	}{
+		{
+			name:          "full",
+			s:             "upload=100; download=200; total=1000; expire=1893499200",
+			want:          &SubscriptionUserinfo{Upload: 100, Download: 200, Total: 1000, Expire: 1893499200},
+			wantRemaining: 700,
+		},
+		{
+			name:          "float and spaces",
+			s:             " Upload = 1.5e3 ;download=0;total=2000",
+			want:          &SubscriptionUserinfo{Upload: 1500, Total: 2000},
+			wantRemaining: 500,
+		},
+		{
+			name:          "overused",
+			s:             "upload=600; download=600; total=1000",
+			want:          &SubscriptionUserinfo{Upload: 600, Download: 600, Total: 1000},
+			wantRemaining: 0,
+		},
+		{
+			name:          "expire only",
+			s:             "expire=1893499200",
+			want:          &SubscriptionUserinfo{Expire: 1893499200},
+			wantRemaining: -1,
+		},
+		{
+			name: "no total or expire",
+			s:    "upload=1; download=2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseSubscriptionUserinfo(tt.s)
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("ParseSubscriptionUserinfo() = %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil || *got != *tt.want {
+				t.Fatalf("ParseSubscriptionUserinfo() = %+v, want %+v", got, tt.want)
+			}
+			if r := got.Remaining(); r != tt.wantRemaining {
+				t.Errorf("Remaining() = %d, want %d", r, tt.wantRemaining)
+			}
+		})
+	}
+}
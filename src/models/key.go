@@ -9,6 +9,7 @@ import (
 type (
 	ProxieInfo struct {
 		Id   ProxieKey
+		Name string // 节点当前名称，仅用于展示
 		Conf *Conf
 	}
 	ProxieTesterType string
@@ -18,6 +19,14 @@ type (
 		GetResult(proxy *ProxieInfo) (any, error)
 		RunTest(proxy *ProxieInfo, transport http.RoundTripper) (any, error)
 	}
+	// ProxieResultDepender 可选实现，返回测试结果所依赖的conf字段，变化时已有结果失效
+	ProxieResultDepender interface {
+		ResultDepends(*Conf) any
+	}
+	// ProxieHeavyTester 可选实现，重任务在静默时段内会被推迟
+	ProxieHeavyTester interface {
+		Heavy() bool
+	}
 )
 
 const CronJobKeyPrefix = "CronJob/"
@@ -35,6 +44,20 @@ func (c *CronJobKey) ToProxiePrefixKey() []byte {
 	return []byte(ProxieKeyPrefix + c.ConfId)
 }
 
+func (c *CronJobKey) ToProxieResultPrefixKey() []byte {
+	return []byte(ProxieResultKeyPrefix + c.ConfId + "::")
+}
+
+const ConfStateKeyPrefix = "ConfState/"
+
+type ConfStateKey struct {
+	ConfId string
+}
+
+func (c *ConfStateKey) ToKey() []byte {
+	return []byte(ConfStateKeyPrefix + c.ConfId)
+}
+
 func (c *CronJobKey) FromKey(_key []byte) error {
 	key := strings.TrimPrefix(string(_key), CronJobKeyPrefix)
 	parts := strings.Split(key, "::")
@@ -48,14 +71,15 @@ func (c *CronJobKey) FromKey(_key []byte) error {
 
 const ProxieKeyPrefix = "Proxie/"
 
+// ProxieKey 节点标识，使用连接参数指纹，节点改名后测试结果不丢失，同名节点不冲突
 type ProxieKey struct {
-	ConfId     string
-	SubName    string
-	ProxieName string
+	ConfId      string
+	SubName     string
+	Fingerprint string // 见 ProxieFingerprint
 }
 
 func (p *ProxieKey) ToKey() []byte {
-	return []byte(ProxieKeyPrefix + strings.Join([]string{p.ConfId, p.SubName, p.ProxieName}, "::"))
+	return []byte(ProxieKeyPrefix + strings.Join([]string{p.ConfId, p.SubName, p.Fingerprint}, "::"))
 }
 
 func (p *ProxieKey) FromKey(_key []byte) error {
@@ -66,7 +90,7 @@ func (p *ProxieKey) FromKey(_key []byte) error {
 	}
 	p.ConfId = parts[0]
 	p.SubName = parts[1]
-	p.ProxieName = parts[2]
+	p.Fingerprint = parts[2]
 	return nil
 }
 
@@ -78,7 +102,7 @@ type ProxieResultKey struct {
 }
 
 func (p *ProxieResultKey) ToKey() []byte {
-	return []byte(ProxieResultKeyPrefix + strings.Join([]string{p.ConfId, p.SubName, p.ProxieName, string(p.Type)}, "::"))
+	return []byte(ProxieResultKeyPrefix + strings.Join([]string{p.ConfId, p.SubName, p.Fingerprint, string(p.Type)}, "::"))
 }
 
 func (p *ProxieResultKey) FromKey(_key []byte) error {
@@ -89,7 +113,119 @@ func (p *ProxieResultKey) FromKey(_key []byte) error {
 	}
 	p.ConfId = parts[0]
 	p.SubName = parts[1]
-	p.ProxieName = parts[2]
+	p.Fingerprint = parts[2]
 	p.Type = ProxieTesterType(parts[3])
 	return nil
 }
+
+const ProxieFailKeyPrefix = "ProxieFail/"
+
+type ProxieFailKey ProxieResultKey
+
+func (p *ProxieFailKey) ToKey() []byte {
+	return []byte(ProxieFailKeyPrefix + strings.Join([]string{p.ConfId, p.SubName, p.Fingerprint, string(p.Type)}, "::"))
+}
+
+const ProxieDelayKeyPrefix = "ProxieDelay/"
+
+// ProxieDelayKey 节点延迟历史
+type ProxieDelayKey ProxieKey
+
+func (p *ProxieDelayKey) ToKey() []byte {
+	return []byte(ProxieDelayKeyPrefix + strings.Join([]string{p.ConfId, p.SubName, p.Fingerprint}, "::"))
+}
+
+const ProxieAliasKeyPrefix = "ProxieAlias/"
+
+// ProxieAliasKey 节点指纹对应的名称，最近使用的在前
+type ProxieAliasKey ProxieKey
+
+func (p *ProxieAliasKey) ToKey() []byte {
+	return []byte(ProxieAliasKeyPrefix + strings.Join([]string{p.ConfId, p.SubName, p.Fingerprint}, "::"))
+}
+
+const MigrationKeyPrefix = "Migration/"
+
+// MigrationKey 已完成的数据迁移
+type MigrationKey struct {
+	Name string
+}
+
+func (k *MigrationKey) ToKey() []byte {
+	return []byte(MigrationKeyPrefix + k.Name)
+}
+
+const KeptNodesKeyPrefix = "KeptNodes/"
+
+// KeptNodesKey conf上次数量限制后保留的节点
+type KeptNodesKey struct {
+	ConfId string
+}
+
+func (k *KeptNodesKey) ToKey() []byte {
+	return []byte(KeptNodesKeyPrefix + k.ConfId)
+}
+
+const NodeIndexKeyPrefix = "NodeIndex/"
+
+// NodeIndexKey conf内节点身份到名称序号的映射
+type NodeIndexKey struct {
+	ConfId string
+}
+
+func (k *NodeIndexKey) ToKey() []byte {
+	return []byte(NodeIndexKeyPrefix + k.ConfId)
+}
+
+const SubInfoKeyPrefix = "SubInfo/"
+
+// SubInfoKey conf内订阅最近一次提取的信息
+type SubInfoKey struct {
+	ConfId  string
+	SubName string
+}
+
+func (k *SubInfoKey) ToKey() []byte {
+	return []byte(SubInfoKeyPrefix + strings.Join([]string{k.ConfId, k.SubName}, "::"))
+}
+
+// ToConfPrefixKey conf内全部订阅信息的前缀
+func (k *SubInfoKey) ToConfPrefixKey() []byte {
+	return []byte(SubInfoKeyPrefix + k.ConfId + "::")
+}
+
+const AlertKeyPrefix = "Alert/"
+
+// AlertKey 已发送的订阅告警，用于在重复间隔内去重
+type AlertKey struct {
+	ConfId  string
+	SubName string
+	Kind    string
+}
+
+func (k *AlertKey) ToKey() []byte {
+	return []byte(AlertKeyPrefix + strings.Join([]string{k.ConfId, k.SubName, k.Kind}, "::"))
+}
+
+const IPPurityKeyPrefix = "IPPurity/"
+
+// IPPurityKey 按出口IP缓存的检测结果，跨节点及conf共享
+type IPPurityKey struct {
+	IP string
+}
+
+func (k *IPPurityKey) ToKey() []byte {
+	return []byte(IPPurityKeyPrefix + k.IP)
+}
+
+const ApiKeyStateKeyPrefix = "ApiKey/"
+
+// ApiKeyStateKey 检测器API密钥用量，KeyId为密钥摘要，不保存明文
+type ApiKeyStateKey struct {
+	Detector string
+	KeyId    string
+}
+
+func (k *ApiKeyStateKey) ToKey() []byte {
+	return []byte(ApiKeyStateKeyPrefix + strings.Join([]string{k.Detector, k.KeyId}, "::"))
+}
@@ -0,0 +1,64 @@
+package models
+
+import "strings"
+
+// ScoringProfile 纯净度合并评分配置，未配置的字段使用默认值
+type ScoringProfile struct {
+	Detectors map[string]float64 `json:"detectors"` // 检测器信任权重，不区分大小写，未配置为1，0为不参与合并
+	Factors   FactorWeights      `json:"factors"`   // 检测器未返回评分时，按风险因子推算评分的加分
+	Threshold float64            `json:"threshold"` // 风险因子判定阈值，判定为真的检测器权重占比达到该值时成立，默认:0.3
+	Floors    ScoreFloors        `json:"floors"`    // 合并结果满足条件时的最低风险评分
+
+	MinAgreement float64 `json:"min_agreement"` // 国家/使用类型一致的检测器权重占比低于该值时视为不确定，默认:0.5
+	MaxSpread    int     `json:"max_spread"`    // 检测器风险评分极差超过该值时视为不确定，0为不判断，默认:50
+}
+
+type FactorWeights struct {
+	Datacenter int `json:"datacenter"` // 默认:30
+	Other      int `json:"other"`      // 默认:20
+	Proxy      int `json:"proxy"`      // 默认:30
+	VPN        int `json:"vpn"`        // 默认:25
+	Tor        int `json:"tor"`        // 默认:40
+	Server     int `json:"server"`     // 默认:15
+	Abuse      int `json:"abuse"`      // 默认:0
+	Bot        int `json:"bot"`        // 默认:0
+}
+
+type ScoreFloors struct {
+	Datacenter int `json:"datacenter"` // 默认:50
+	Other      int `json:"other"`      // 默认:30
+	Proxy      int `json:"proxy"`      // 默认:20
+	VPN        int `json:"vpn"`        // 默认:10
+}
+
+func DefaultScoringProfile() ScoringProfile {
+	return ScoringProfile{
+		Factors: FactorWeights{
+			Datacenter: 30,
+			Other:      20,
+			Proxy:      30,
+			VPN:        25,
+			Tor:        40,
+			Server:     15,
+		},
+		Threshold: 0.3,
+		Floors: ScoreFloors{
+			Datacenter: 50,
+			Other:      30,
+			Proxy:      20,
+			VPN:        10,
+		},
+		MinAgreement: 0.5,
+		MaxSpread:    50,
+	}
+}
+
+// DetectorWeight 返回检测器的信任权重，负数按0处理
+func (p *ScoringProfile) DetectorWeight(name string) float64 {
+	for k, v := range p.Detectors {
+		if strings.EqualFold(k, name) {
+			return max(v, 0)
+		}
+	}
+	return 1
+}
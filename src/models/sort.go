@@ -0,0 +1,47 @@
+package models
+
+import "encoding/json"
+
+const (
+	SortByDelay     = "delay"     // 延迟从低到高
+	SortBySpeed     = "speed"     // 速度从高到低
+	SortByPurity    = "purity"    // 风险评分从低到高
+	SortByStability = "stability" // 延迟历史稳定性从高到低
+	SortByCost      = "cost"      // 按倍率折算后的速度从高到低
+	SortByComposite = "composite" // 按权重综合以上各项
+)
+
+// SortConf 国家组及组内节点的排序配置，未配置的字段使用默认值
+type SortConf struct {
+	Strategy     string             `json:"strategy"`      // 排序策略: delay/speed/purity/stability/cost/composite，默认:delay
+	Weights      map[string]float64 `json:"weights"`       // composite 各项权重，键为以上策略名，默认各项为1，cost为0，配置后整体替换默认值
+	CountryOrder []string           `json:"country_order"` // 固定排在前面的国家，按顺序，如 ["HK","JP","SG","US"]
+}
+
+func DefaultSortConf() SortConf {
+	return SortConf{
+		Strategy: SortByDelay,
+		Weights: map[string]float64{
+			SortByDelay:     1,
+			SortBySpeed:     1,
+			SortByPurity:    1,
+			SortByStability: 1,
+			SortByCost:      0,
+		},
+	}
+}
+
+// UnmarshalJSON conf在默认值上解码，map会与默认值合并，配置了weights时改为替换默认权重
+func (s *SortConf) UnmarshalJSON(data []byte) error {
+	type Alias SortConf
+	var raw struct {
+		Weights map[string]float64 `json:"weights"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw.Weights != nil {
+		s.Weights = nil
+	}
+	return json.Unmarshal(data, (*Alias)(s))
+}
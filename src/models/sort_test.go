@@ -0,0 +1,40 @@
+package models
+
+import (
+	"encoding/json"
+	"maps"
+	"testing"
+)
+
+func TestSortConf_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want map[string]float64
+	}{
+		{
+			name: "weights replace defaults",
+			data: `{"sort": {"strategy": "composite", "weights": {"purity": 1}}}`,
+			want: map[string]float64{SortByPurity: 1},
+		},
+		{
+			name: "defaults without weights",
+			data: `{"sort": {"strategy": "composite"}}`,
+			want: DefaultSortConf().Weights,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var conf Conf
+			if err := json.Unmarshal([]byte(tt.data), &conf); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			if conf.Sort.Strategy != SortByComposite {
+				t.Errorf("Strategy = %s, want %s", conf.Sort.Strategy, SortByComposite)
+			}
+			if !maps.Equal(conf.Sort.Weights, tt.want) {
+				t.Errorf("Weights = %v, want %v", conf.Sort.Weights, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ocyss/sub-store-lab/src/models"
+	"resty.dev/v3"
+)
+
+const (
+	KindExpire  = "expire"  // 即将到期或已到期
+	KindTraffic = "traffic" // 剩余流量不足
+)
+
+// sendTimeout 单次告警请求的超时时间
+const sendTimeout = 10 * time.Second
+
+// Alert 订阅告警，json格式时作为请求体发送
+type Alert struct {
+	ConfId     string    `json:"conf_id"`
+	SubName    string    `json:"sub_name"`
+	Kind       string    `json:"kind"`
+	Message    string    `json:"message"`
+	ExpireDate string    `json:"expire_date,omitempty"`
+	DaysLeft   int       `json:"days_left"`
+	Traffic    string    `json:"traffic,omitempty"`
+	Remaining  int64     `json:"remaining_bytes"` // 剩余流量(字节)，未知为-1
+	At         time.Time `json:"at"`
+}
+
+// Send 按配置的格式将告警发送到webhook
+func Send(ctx context.Context, conf *models.AlertConf, alert *Alert) error {
+	var body any = alert
+	if conf.Format == models.AlertFormatTelegram {
+		body = map[string]any{
+			"chat_id": conf.ChatId,
+			"text":    fmt.Sprintf("⚠️ [%s] %s", alert.ConfId, alert.Message),
+		}
+	}
+
+	client := resty.New().SetTimeout(sendTimeout)
+	defer client.Close()
+
+	resp, err := client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetBody(body).
+		Post(conf.Webhook)
+	if err != nil {
+		return fmt.Errorf("发送告警失败: %w", err)
+	}
+	if resp.IsError() {
+		return fmt.Errorf("发送告警失败: %s", resp.Status())
+	}
+	return nil
+}
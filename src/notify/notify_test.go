@@ -0,0 +1,164 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ocyss/sub-store-lab/src/env"
+	"github.com/ocyss/sub-store-lab/src/models"
+)
+
+func TestEvaluate(t *testing.T) {
+	now := time.Date(2025, 6, 10, 15, 0, 0, 0, time.Local)
+	conf := models.DefaultAlertConf()
+	tests := []struct {
+		name      string
+		state     models.SubscriptionState
+		wantKinds []string
+		wantDays  int
+	}{
+		{"healthy", models.SubscriptionState{ExpireDate: "2025-07-01", Remaining: 100 << 30}, nil, 0},
+		{"expiring", models.SubscriptionState{ExpireDate: "2025-06-13", Remaining: -1}, []string{KindExpire}, 3},
+		{"expired", models.SubscriptionState{ExpireDate: "2025-06-01", Remaining: -1}, []string{KindExpire}, -9},
+		{"low traffic", models.SubscriptionState{ExpireDate: "长期有效", Remaining: 5 << 30}, []string{KindTraffic}, 0},
+		{"both", models.SubscriptionState{ExpireDate: "2025-06-10", Remaining: 0}, []string{KindExpire, KindTraffic}, 0},
+		{"unknown", models.SubscriptionState{Remaining: -1}, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.state.SubName = "A"
+			alerts := Evaluate(&conf, &tt.state, now)
+			if len(alerts) != len(tt.wantKinds) {
+				t.Fatalf("Evaluate() = %d alerts, want %v", len(alerts), tt.wantKinds)
+			}
+			for i, alert := range alerts {
+				if alert.Kind != tt.wantKinds[i] {
+					t.Errorf("alerts[%d].Kind = %s, want %s", i, alert.Kind, tt.wantKinds[i])
+				}
+				if alert.Kind == KindExpire && alert.DaysLeft != tt.wantDays {
+					t.Errorf("alerts[%d].DaysLeft = %d, want %d", i, alert.DaysLeft, tt.wantDays)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckSubscriptions(t *testing.T) {
+	tests := []struct {
+		format string
+		check  func(t *testing.T, body map[string]any)
+	}{
+		{
+			format: models.AlertFormatJSON,
+			check: func(t *testing.T, body map[string]any) {
+				if body["conf_id"] != "conf" || body["sub_name"] != "A" || body["kind"] != KindTraffic {
+					t.Errorf("body = %v", body)
+				}
+				if body["remaining_bytes"] != float64(1<<30) {
+					t.Errorf("remaining_bytes = %v, want %d", body["remaining_bytes"], 1<<30)
+				}
+			},
+		},
+		{
+			format: models.AlertFormatTelegram,
+			check: func(t *testing.T, body map[string]any) {
+				text, _ := body["text"].(string)
+				if body["chat_id"] != "42" || !strings.Contains(text, "订阅 A 剩余流量 1GB") {
+					t.Errorf("body = %v", body)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var bodies []map[string]any
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body map[string]any
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("decode body: %v", err)
+				}
+				bodies = append(bodies, body)
+			}))
+			defer server.Close()
+
+			conf := models.DefaultAlertConf()
+			conf.Webhook = server.URL
+			conf.Format = tt.format
+			conf.ChatId = "42"
+			CheckSubscriptions(conf, []models.SubscriptionState{
+				{ConfId: "conf", SubName: "A", Traffic: "1 GB", Remaining: 1 << 30, ExpireDate: "长期有效"},
+				{ConfId: "conf", SubName: "B", Traffic: "500 GB", Remaining: 500 << 30},
+			})
+			if len(bodies) != 1 {
+				t.Fatalf("received %d requests, want 1", len(bodies))
+			}
+			tt.check(t, bodies[0])
+		})
+	}
+
+	t.Run("error status", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer server.Close()
+		err := Send(t.Context(), &models.AlertConf{Webhook: server.URL}, &Alert{Message: "test"})
+		if err == nil {
+			t.Error("Send() should fail on error status")
+		}
+	})
+}
+
+func TestCheckSubscriptions_Repeat(t *testing.T) {
+	if err := env.InitMemoryDB(); err != nil {
+		t.Fatalf("InitMemoryDB() error = %v", err)
+	}
+	defer env.CloseDB()
+
+	var requests, failures atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		if failures.Load() > 0 {
+			failures.Add(-1)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	conf := models.DefaultAlertConf()
+	conf.Webhook = server.URL
+	conf.Repeat = "1h"
+	states := func(sub string) []models.SubscriptionState {
+		return []models.SubscriptionState{{ConfId: "conf", SubName: sub, Traffic: "1 GB", Remaining: 1 << 30}}
+	}
+
+	// 多个请求同时检查同一订阅只发送一次
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() { CheckSubscriptions(conf, states("A")) })
+	}
+	wg.Wait()
+	if got := requests.Load(); got != 1 {
+		t.Fatalf("concurrent checks sent %d alerts, want 1", got)
+	}
+
+	// 重复间隔内不再发送
+	CheckSubscriptions(conf, states("A"))
+	if got := requests.Load(); got != 1 {
+		t.Errorf("repeated check sent %d alerts, want 1", got)
+	}
+
+	// 发送失败时不保留记录，下次检查重试
+	failures.Store(1)
+	CheckSubscriptions(conf, states("B"))
+	CheckSubscriptions(conf, states("B"))
+	CheckSubscriptions(conf, states("B"))
+	if got := requests.Load(); got != 3 {
+		t.Errorf("retry after failure sent %d requests, want 3", got)
+	}
+}
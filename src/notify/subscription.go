@@ -0,0 +1,141 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/ocyss/sub-store-lab/src/env"
+	"github.com/ocyss/sub-store-lab/src/models"
+	"github.com/ocyss/sub-store-lab/src/utils"
+)
+
+// defaultRepeat repeat 无效时的告警重复间隔
+const defaultRepeat = 24 * time.Hour
+
+var errAlertSent = errors.New("告警已发送")
+
+// Evaluate 按阈值检查订阅信息，返回需要发送的告警
+func Evaluate(conf *models.AlertConf, state *models.SubscriptionState, now time.Time) []*Alert {
+	var alerts []*Alert
+	newAlert := func(kind, message string) *Alert {
+		return &Alert{
+			ConfId:     state.ConfId,
+			SubName:    state.SubName,
+			Kind:       kind,
+			Message:    message,
+			ExpireDate: state.ExpireDate,
+			Traffic:    state.Traffic,
+			Remaining:  state.Remaining,
+			At:         now,
+		}
+	}
+
+	if conf.ExpireDays > 0 {
+		if expire, err := time.ParseInLocation(time.DateOnly, state.ExpireDate, now.Location()); err == nil {
+			today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+			days := int(math.Round(expire.Sub(today).Hours() / 24))
+			if days <= conf.ExpireDays {
+				message := fmt.Sprintf("订阅 %s 将于 %s 到期，剩余 %d 天", state.SubName, state.ExpireDate, days)
+				if days < 0 {
+					message = fmt.Sprintf("订阅 %s 已于 %s 到期", state.SubName, state.ExpireDate)
+				}
+				alert := newAlert(KindExpire, message)
+				alert.DaysLeft = days
+				alerts = append(alerts, alert)
+			}
+		}
+	}
+
+	if conf.MinTraffic != "" && state.Remaining >= 0 {
+		minTraffic, err := utils.ParseBytes(conf.MinTraffic)
+		if err != nil {
+			slog.Warn("alert.min_traffic无效, 不检查流量", "conf", state.ConfId, "error", err)
+		} else if state.Remaining < minTraffic {
+			alerts = append(alerts, newAlert(KindTraffic, fmt.Sprintf("订阅 %s 剩余流量 %s，低于 %s",
+				state.SubName, utils.HumanBytes(state.Remaining), conf.MinTraffic)))
+		}
+	}
+	return alerts
+}
+
+// CheckSubscriptions 检查conf内各订阅并发送告警，同一订阅的同类告警在repeat间隔内只发送一次
+func CheckSubscriptions(conf models.AlertConf, states []models.SubscriptionState) {
+	if conf.Webhook == "" {
+		return
+	}
+	repeat, err := utils.ParseDuration(conf.Repeat)
+	if err != nil || repeat <= 0 {
+		repeat = defaultRepeat
+	}
+
+	now := time.Now()
+	for i := range states {
+		for _, alert := range Evaluate(&conf, &states[i], now) {
+			key := models.AlertKey{ConfId: alert.ConfId, SubName: alert.SubName, Kind: alert.Kind}
+			// 每次请求都会并发检查，发送前先占用告警记录，避免重复发送
+			if !reserve(&key, alert, repeat) {
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			err := Send(ctx, &conf, alert)
+			cancel()
+			if err != nil {
+				slog.Warn("订阅告警发送失败", "conf", alert.ConfId, "sub", alert.SubName, "kind", alert.Kind, "error", err)
+				release(&key)
+				continue
+			}
+			slog.Info("订阅告警已发送", "conf", alert.ConfId, "sub", alert.SubName, "message", alert.Message)
+		}
+	}
+}
+
+// reserve 在同一事务中检查并写入告警记录，返回false表示重复间隔内已发送或正在发送
+func reserve(key *models.AlertKey, alert *Alert, repeat time.Duration) bool {
+	db := env.GetDB()
+	if db == nil {
+		return true
+	}
+	err := db.Update(func(txn *badger.Txn) error {
+		if _, err := txn.Get(key.ToKey()); err == nil {
+			return errAlertSent
+		} else if !errors.Is(err, badger.ErrKeyNotFound) {
+			return err
+		}
+		data, err := json.Marshal(alert)
+		if err != nil {
+			return err
+		}
+		return txn.SetEntry(badger.NewEntry(key.ToKey(), data).WithTTL(repeat))
+	})
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, errAlertSent), errors.Is(err, badger.ErrConflict):
+		// 并发占用同一记录时后提交的事务冲突，由先提交的一方发送
+		return false
+	default:
+		// 记录读写失败时仍发送，与未启用数据库时一致
+		slog.Warn("failed to save alert state", "key", string(key.ToKey()), "error", err)
+		return true
+	}
+}
+
+// release 发送失败时删除告警记录，下次检查时重试
+func release(key *models.AlertKey) {
+	db := env.GetDB()
+	if db == nil {
+		return
+	}
+	err := db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(key.ToKey())
+	})
+	if err != nil {
+		slog.Warn("failed to delete alert state", "key", string(key.ToKey()), "error", err)
+	}
+}
@@ -11,16 +11,71 @@ async function operator(...args) {
                 // id: "", // 指定当前订阅id
                 // purity_cron: "0 2 */3 * *",// 纯净度测试 cron表达式
                 // speed_cron: "0 3 * * *",// 速度/延迟测试 cron表达式
+                // timezone: "Asia/Shanghai", // cron时区，默认使用环境变量 LAB_TIMEZONE 或容器本地时区
+                // quiet_hours: "19:00-23:30", // 静默时段，逗号分割，期间推迟测速等重任务
+                // jitter: 120, // 定时任务随机延后启动的最大秒数，默认:120
                 // speed_test_url: "", // 测速下载Url
                 // min_speed: "256",// 最低测速结果(KB/s)，低于此值舍弃，默认:256
                 // download_timeout: "8",// 下载测试时间(秒)，与下载链接大小相关。默认:8
                 // download_mb: "20",// 单节点测速下载数据大小(MB)限制，0为不限，默认:20
+                // result_ttl: { purity: "3d", speed: "26h" }, // 各测试器结果有效期，支持d/h/m，默认由cron间隔推导
+                // stale_ttl: "1d", // 结果过期后仍保留使用并标记 _lab_stale 的时长，默认为一个cron间隔
+                // retry_times: 2, // 单次运行中临时性失败(超时/连接重置/限流等)的重试次数，默认:2
+                // quarantine_after: 3, // 连续失败多少次后隔离节点，隔离节点降低测试频率，0为不隔离，默认:3
+                // drop_quarantined: false, // 脚本返回时丢弃隔离中的节点
+                // max_per_country: 0, // 每个国家最多保留的节点数，按 sort 选择，上次保留的节点优先，0为不限
+                // max_per_subscription: 0, // 每个国家内每个订阅最多保留的节点数，0为不限
+                // max_total: 0, // 最多保留的节点总数，0为不限
+                // dedup: { // 跨订阅去重，多个订阅共享同一服务器或出口时只保留一个订阅的副本，保留的节点标记 _lab_shared_subs
+                //     by: ["fingerprint", "exit_ip"], // 判断重复的依据: fingerprint 连接参数相同，exit_ip 出口IP相同，按顺序依次去重，默认不去重
+                //     keep: "best", // 保留规则: best 按 sort 最优，priority 订阅顺序靠前，cost 倍率最低，默认:best
+                // },
+                // mismatch_action: "mark", // 节点名称声明的国家与检测结果不一致时: mark 在国家代码后标记❗，drop 丢弃，regroup 按声明的国家分组，默认:mark
+                // scoring: { // 纯净度合并评分，仅需填写要修改的项
+                //     detectors: { IPQuality: 2, IPApi: 0.5 }, // 检测器信任权重，默认1，0为不参与合并
+                //     factors: { datacenter: 30, other: 20, proxy: 30, vpn: 25, tor: 40, server: 15, abuse: 0, bot: 0 }, // 检测器无评分时按风险因子推算的加分
+                //     threshold: 0.3, // 风险因子成立所需的检测器权重占比
+                //     floors: { datacenter: 50, other: 30, proxy: 20, vpn: 10 }, // 合并结果的最低风险评分
+                //     min_agreement: 0.5, // 国家/使用类型一致的检测器权重占比低于该值时视为不确定
+                //     max_spread: 50, // 检测器风险评分极差超过该值时视为不确定，0为不判断
+                // },
+                // filter: "", // 节点过滤表达式，结果为假的节点被丢弃，各子句丢弃的节点数见 /api/report
+                //     示例: 'purity.risk < 40 && usage == "Residential" && speed_mbps > 50 && !country in ["CN"]'
+                //     字段: name sub delay speed_mbps stability rate quarantined stale country claimed_country mismatch usage city region asn org ip
+                //     purity.risk purity.proxy purity.vpn purity.tor purity.server purity.abuse purity.bot，未知的值参与比较时结果为假
+                // sort: { // 国家组及组内节点排序，组内仍按订阅顺序分段
+                //     strategy: "delay", // delay 延迟，speed 速度，purity 风险评分，stability 延迟历史稳定性，cost 按倍率折算的速度，composite 综合，默认:delay
+                //     weights: { delay: 1, speed: 1, purity: 1, stability: 1, cost: 0 }, // composite 各项权重
+                //     country_order: ["HK", "JP", "SG", "US"], // 固定排在前面的国家，其余按策略排序
+                // },
                 // keyword_keep: "", // 关键词保留，| 竖线分割, 示例: 福利|家宽|流媒
+                // info_patterns: { // 订阅信息节点的识别正则，仅需填写要修改的项，默认同时识别中英文，Sub-Store 订阅配置了 subUserinfo 时优先使用其中的流量及到期时间
+                //     traffic: "", // 剩余流量，分组1为数值，分组2为单位，如 "剩余流量[:：]\\s*([\\d.]+)\\s*(GB|MB|TB)?"
+                //     reset: "", // 距离重置的天数，分组1为天数
+                //     expire: "", // 到期日期，分组1为日期或长期有效/never
+                //     notice: "", // 公告等其他信息节点，仅从节点中移除
+                // },
+                // alert: { // 订阅即将到期或剩余流量不足时告警，同一订阅的同类告警在 repeat 间隔内只发送一次
+                //     webhook: "", // 告警地址，为空不告警，json格式POST告警内容 {conf_id, sub_name, kind, message, expire_date, days_left, traffic, remaining_bytes, at}
+                //     format: "json", // json 或 telegram，telegram时webhook为 https://api.telegram.org/bot<token>/sendMessage
+                //     chat_id: "", // telegram 的 chat_id
+                //     expire_days: 3, // 距离到期不超过该天数时告警，0为不检查
+                //     min_traffic: "10GB", // 剩余流量低于该值时告警，为空不检查
+                //     repeat: "1d", // 同类告警重复发送的最小间隔
+                // },
+                // name_template: "", // 节点名称模板(Go text/template)，为空使用默认格式，无效时使用默认格式并在首个节点提示错误
+                //     可用字段: .Flag .Country .Mismatch .ClaimedCountry .Index .Delay .Speed .SpeedMbps .RiskScore(未知为-1) .PurityIcon .TypeIcon
+                //     .UsageType .IP .City .Region .ASN .Org .Rate .Keywords .Sub .OldName .Stale，函数: join upper lower
+                //     暂无流媒体解锁字段，需等待解锁测试器
+                //     示例: "{{.Flag}}{{.Country}}_{{.Index}} {{.City}} AS{{.ASN}} {{.Delay}}ms{{.Rate}}{{.PurityIcon}}"
+                // proxy_groups: false, // 返回 { proxies, "proxy-groups" }，按 override.yaml 的 lab-groups 生成各国家 url-test/fallback、家宽及低风险策略组，适用于接受完整配置的平台
                 // purity_icon:"🖤|🩵|💙|💛|🧡|❤️", // 数量要严格一致并用竖线|分割，避免emoji分割错误
                 // type_icon:"🪨|🏠|🕋",
+                // uncertain_icon: "❔", // 检测器分歧较大时替代纯净度图标，默认不替代
             },
             args
         }),
     }).then(r => r.json())
-    return resp
+    // 开启 proxy_groups 时返回对象，Sub-store 节点操作仅使用其中的节点
+    return Array.isArray(resp) ? resp : resp.proxies
 }
\ No newline at end of file
@@ -0,0 +1,47 @@
+package tester
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/ocyss/sub-store-lab/src/models"
+)
+
+// aliasTTL 节点名称记录的保留时间
+const aliasTTL = time.Hour * 24 * 30
+
+// maxProxieAliases 每个节点保留的名称数量
+const maxProxieAliases = 5
+
+// RecordAlias 记录节点指纹当前使用的名称，最近使用的在前
+func RecordAlias(txn *badger.Txn, key models.ProxieKey, name string) error {
+	aliasKey := (*models.ProxieAliasKey)(&key).ToKey()
+	var aliases []string
+	item, err := txn.Get(aliasKey)
+	if err == nil {
+		if err := item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &aliases)
+		}); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, badger.ErrKeyNotFound) {
+		return err
+	}
+	aliases = addAlias(aliases, name)
+	data, err := json.Marshal(aliases)
+	if err != nil {
+		return err
+	}
+	return txn.SetEntry(badger.NewEntry(aliasKey, data).WithTTL(aliasTTL))
+}
+
+func addAlias(aliases []string, name string) []string {
+	aliases = slices.DeleteFunc(aliases, func(alias string) bool {
+		return alias == name
+	})
+	aliases = append([]string{name}, aliases...)
+	return aliases[:min(len(aliases), maxProxieAliases)]
+}
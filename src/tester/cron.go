@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/dgraph-io/badger/v4"
@@ -16,14 +17,23 @@ import (
 type CronManager struct {
 	scheduler gocron.Scheduler
 	jobs      map[models.CronJobKey]*CronJob
+	confs     map[string]*ConfState
+	mu        sync.Mutex
 }
 
 var cronManager *CronManager
 
+// ConfState 记录每个conf最后一次生效的完整内容及摘要
+type ConfState struct {
+	Hash      string
+	Conf      models.Conf
+	UpdatedAt time.Time
+}
+
 type CronTask struct {
 	Key          models.CronJobKey
 	Conf         models.Conf
-	FilterProxie map[models.ProxieKey]struct{}
+	FilterProxie map[models.ProxieKey]struct{} `json:"-"`
 }
 
 type CronJob struct {
@@ -41,7 +51,7 @@ func GetCronJob(conf *models.Conf, t models.ProxieTester) CronJob {
 			},
 			Conf: *conf,
 		},
-		CronExpr: t.Cron(conf),
+		CronExpr: cronExprOf(conf, t),
 	}
 }
 
@@ -49,61 +59,219 @@ func (c *CronJob) Job() gocron.Job {
 	return c.job
 }
 
+// Run 立即在后台运行任务，不随机延后启动，重任务仍受静默时段限制
 func (c *CronJob) Run() error {
+	cronManager.mu.Lock()
 	if c.job == nil {
+		cronManager.mu.Unlock()
 		return fmt.Errorf("job is nil")
 	}
-	return c.job.RunNow()
+	// 复制任务，避免与conf变化时的更新竞争
+	task := c.CronTask
+	cronManager.mu.Unlock()
+	go runTask(&task, false)
+	return nil
 }
 
+// RunTask 立即运行指定任务，重任务仍受静默时段限制
 func (c *CronJob) RunTask(task *CronTask) {
-	taskFunc(task)
+	runTask(task, false)
 }
 
 func InitCron() {
 	logger := env.GetLogger()
 	s, err := gocron.NewScheduler(gocron.WithLogger(logger))
 	if err != nil {
-		// handle error
+		slog.Error("failed to create cron scheduler", "error", err)
+		panic(err)
 	}
 	cronManager = &CronManager{
 		scheduler: s,
 		jobs:      make(map[models.CronJobKey]*CronJob),
+		confs:     make(map[string]*ConfState),
 	}
 	cronManager.scheduler.Start()
-	env.QueryDbPrefix(func(txn *badger.Txn, k []byte, v CronJob) error {
+	err = env.QueryDbPrefix(func(txn *badger.Txn, k []byte, v ConfState) error {
+		cronManager.confs[v.Conf.Id] = &v
+		return nil
+	}, []byte(models.ConfStateKeyPrefix), false)
+	if err != nil {
+		slog.Warn("failed to restore conf state from database", "error", err)
+	}
+	err = env.QueryDbPrefix(func(txn *badger.Txn, k []byte, v CronJob) error {
+		if GetTester(v.Key.Type) == nil {
+			return nil
+		}
 		cronManager.jobs[v.Key] = &v
 		return nil
 	}, []byte(models.CronJobKeyPrefix), false)
+	if err != nil {
+		slog.Warn("failed to restore cron job from database", "error", err)
+	}
+	for _, job := range cronManager.jobs {
+		cronManager.schedule(job)
+	}
+}
+
+// SyncConf 检测conf是否变化并更新该conf下的全部任务，返回结果已被清理、需要重新测试的测试类型
+// 需在读取测试结果前调用，避免清理与读取交错
+func (m *CronManager) SyncConf(conf *models.Conf) []models.ProxieTesterType {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.syncConf(conf)
 }
 
 func (m *CronManager) GetJob(j CronJob) *CronJob {
-	if job, ok := cronManager.jobs[j.Key]; ok {
-		if job.CronExpr == j.CronExpr && job.Conf.Eq(&j.Conf) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.syncConf(&j.Conf)
+	if job, ok := m.jobs[j.Key]; ok {
+		if job.job != nil && job.CronExpr == j.CronExpr && job.Conf.Eq(&j.Conf) {
 			return job
 		}
 		job.CronExpr = j.CronExpr
 		job.Conf = j.Conf
-		cronManager.scheduler.Update(job.job.ID(), gocron.CronJob(job.CronExpr, false), gocron.NewTask(taskFunc, job))
+		m.schedule(job)
+		m.saveJob(job)
 		return job
 	}
-	return m.CreateJob(j)
+	return m.createJob(j)
 }
 
 func (m *CronManager) CreateJob(j CronJob) *CronJob {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.createJob(j)
+}
+
+func (m *CronManager) createJob(j CronJob) *CronJob {
 	cronJob := &CronJob{
 		CronTask: j.CronTask,
 		CronExpr: j.CronExpr,
 		job:      nil,
 	}
-	cronManager.jobs[j.Key] = cronJob
-	job, err := m.scheduler.NewJob(gocron.CronJob(cronJob.CronExpr, false), gocron.NewTask(taskFunc, cronJob))
+	m.jobs[j.Key] = cronJob
+	m.schedule(cronJob)
+	m.saveJob(cronJob)
+	return cronJob
+}
+
+// schedule 创建或更新调度器中的任务
+func (m *CronManager) schedule(cronJob *CronJob) {
+	var (
+		job gocron.Job
+		err error
+	)
+	definition := gocron.CronJob(cronJob.CronExpr, false)
+	// 传入副本，运行中的任务不受后续修改影响
+	cronTask := cronJob.CronTask
+	task := gocron.NewTask(scheduledTask, &cronTask)
+	if cronJob.job == nil {
+		job, err = m.scheduler.NewJob(definition, task)
+	} else {
+		job, err = m.scheduler.Update(cronJob.job.ID(), definition, task)
+	}
 	if err != nil {
-		slog.Error("failed to create cron job", "key", cronJob.Key, "error", err)
-		return cronJob
+		slog.Error("failed to schedule cron job", "key", cronJob.Key, "expr", cronJob.CronExpr, "error", err)
+		return
 	}
 	cronJob.job = job
-	return cronJob
+}
+
+func (m *CronManager) saveJob(cronJob *CronJob) {
+	err := env.GetDB().Update(func(txn *badger.Txn) error {
+		data, err := json.Marshal(cronJob)
+		if err != nil {
+			return err
+		}
+		return txn.Set(cronJob.Key.ToKey(), data)
+	})
+	if err != nil {
+		slog.Error("failed to save cron job", "key", cronJob.Key, "error", err)
+	}
+}
+
+// syncConf 检测conf是否变化，变化时更新该conf下的全部任务，并清理依赖变更字段的测试结果
+func (m *CronManager) syncConf(conf *models.Conf) (invalidated []models.ProxieTesterType) {
+	hash := conf.Hash()
+	old, ok := m.confs[conf.Id]
+	if ok && old.Hash == hash {
+		return nil
+	}
+	state := &ConfState{
+		Hash:      hash,
+		Conf:      *conf,
+		UpdatedAt: time.Now(),
+	}
+	m.confs[conf.Id] = state
+
+	if ok {
+		slog.Info("conf changed, re-plan jobs", "id", conf.Id, "old", old.Hash, "new", hash)
+		for name, t := range GetTesters() {
+			if d, ok := t.(models.ProxieResultDepender); ok {
+				if models.HashOf(d.ResultDepends(&old.Conf)) != models.HashOf(d.ResultDepends(conf)) {
+					invalidateResults(conf.Id, name)
+					invalidated = append(invalidated, name)
+				}
+			}
+			key := models.CronJobKey{ConfId: conf.Id, Type: name}
+			if job, ok := m.jobs[key]; ok {
+				// 调度器持有任务副本，conf变化时需重新调度
+				job.Conf = *conf
+				job.CronExpr = cronExprOf(conf, t)
+				m.schedule(job)
+				m.saveJob(job)
+			}
+		}
+	}
+
+	err := env.GetDB().Update(func(txn *badger.Txn) error {
+		data, err := json.Marshal(state)
+		if err != nil {
+			return err
+		}
+		key := models.ConfStateKey{ConfId: conf.Id}
+		return txn.Set(key.ToKey(), data)
+	})
+	if err != nil {
+		slog.Error("failed to save conf state", "id", conf.Id, "error", err)
+	}
+	return invalidated
+}
+
+// invalidateResults 删除某个conf下指定测试类型的全部结果，分批写入避免结果过多时超出单个事务大小
+func invalidateResults(confId string, testerType models.ProxieTesterType) {
+	db := env.GetDB()
+	prefix := (&models.CronJobKey{ConfId: confId}).ToProxieResultPrefixKey()
+	var keys [][]byte
+	err := db.View(func(txn *badger.Txn) error {
+		return iteratePrefix(txn, string(prefix), func(item *badger.Item, _ string) error {
+			var resultKey models.ProxieResultKey
+			if err := resultKey.FromKey(item.Key()); err != nil || resultKey.Type != testerType {
+				return nil
+			}
+			keys = append(keys, item.KeyCopy(nil))
+			return nil
+		})
+	})
+	if err == nil {
+		wb := db.NewWriteBatch()
+		defer wb.Cancel()
+		for _, key := range keys {
+			if err = wb.Delete(key); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = wb.Flush()
+		}
+	}
+	if err != nil {
+		slog.Error("failed to invalidate results", "id", confId, "type", testerType, "error", err)
+		return
+	}
+	slog.Info("results invalidated", "id", confId, "type", testerType, "count", len(keys))
 }
 
 func taskFunc(task *CronTask) {
@@ -142,23 +310,41 @@ func taskFunc(task *CronTask) {
 				"当前进度", count,
 				"总数", len(proxies),
 				"百分比", int(float64(count)*100.0/float64(len(proxies))),
-				"当前代理", name,
+				"当前代理", utils.GetD(proxie, "name", name.Fingerprint),
 			)
 		}
-		time.Sleep(time.Second * 1)
-		t, err := utils.CreateMihomoProxy(proxie)
+		proxyInfo := &models.ProxieInfo{
+			Id:   name,
+			Name: utils.GetD(proxie, "name", ""),
+			Conf: &task.Conf,
+		}
+		failKey := models.ProxieFailKey{ProxieKey: name, Type: task.Key.Type}
+		failure, err := GetFailure(task.Key.Type, proxyInfo)
 		if err != nil {
-			slog.Error("failed to create mihomo proxy", "key", task.Key, "proxie", name, "error", err)
+			slog.Warn("failed to get failure", "key", task.Key, "proxie", name, "error", err)
+		}
+		// 隔离中的节点降低测试频率，指定节点运行时同样跳过，新节点没有失败记录不受影响
+		if failure != nil && failure.Quarantined && failure.SkipRuns > 0 {
+			failure.SkipRuns--
+			if err := saveFailure(failKey, failure); err != nil {
+				slog.Error("failed to save failure", "key", task.Key, "proxie", name, "error", err)
+			}
+			slog.Debug("skip quarantined proxie", "key", task.Key, "proxie", name, "skipRuns", failure.SkipRuns)
 			continue
 		}
-		val, err := tester.RunTest(&models.ProxieInfo{
-			Id:   name,
-			Conf: &task.Conf,
-		}, t)
+		time.Sleep(time.Second * 1)
+		val, attempts, err := runWithRetry(tester, proxyInfo, proxie)
 		if err != nil {
-			slog.Error("failed to run test", "key", task.Key, "proxie", name, "error", err)
+			failure = recordFailure(failKey, failure, &task.Conf, err, attempts)
+			slog.Error("failed to run test", "key", task.Key, "proxie", name, "class", failure.Class,
+				"attempts", attempts, "consecutiveFails", failure.ConsecutiveFails, "quarantined", failure.Quarantined, "error", err)
 			continue
 		}
+		if failure != nil {
+			if err := clearFailure(failKey); err != nil {
+				slog.Error("failed to clear failure", "key", task.Key, "proxie", name, "error", err)
+			}
+		}
 		err = db.Update(func(txn *badger.Txn) error {
 			data, err := json.Marshal(val)
 			if err != nil {
@@ -168,7 +354,7 @@ func taskFunc(task *CronTask) {
 				ProxieKey: name,
 				Type:      task.Key.Type,
 			}
-			return txn.SetEntry(badger.NewEntry(resultKey.ToKey(), data).WithTTL(time.Hour * 48))
+			return txn.SetEntry(badger.NewEntry(resultKey.ToKey(), data).WithTTL(StoreTTL(&task.Conf, tester)))
 		})
 		if err != nil {
 			slog.Error("failed to update result", "key", task.Key, "proxie", name, "error", err)
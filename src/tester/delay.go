@@ -0,0 +1,90 @@
+package tester
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/ocyss/sub-store-lab/src/env"
+	"github.com/ocyss/sub-store-lab/src/models"
+)
+
+// delayHistoryTTL 延迟历史保留时间，节点长期未出现在订阅中时自动清理
+const delayHistoryTTL = time.Hour * 24 * 30
+
+// maxDelaySamples 每个节点保留的延迟样本数
+const maxDelaySamples = 20
+
+// DelaySample 单次延迟测试结果，Delay为0表示失败
+type DelaySample struct {
+	At    time.Time
+	Delay uint16
+}
+
+// DelayHistory 节点最近的延迟测试结果，用于计算稳定性
+type DelayHistory struct {
+	Samples []DelaySample
+}
+
+// Stability 返回0-1的稳定性，成功率乘以延迟抖动系数，无样本时为0
+func (h *DelayHistory) Stability() float64 {
+	if h == nil || len(h.Samples) == 0 {
+		return 0
+	}
+	var delays []float64
+	for _, s := range h.Samples {
+		if s.Delay > 0 {
+			delays = append(delays, float64(s.Delay))
+		}
+	}
+	if len(delays) == 0 {
+		return 0
+	}
+	success := float64(len(delays)) / float64(len(h.Samples))
+
+	var sum float64
+	for _, d := range delays {
+		sum += d
+	}
+	mean := sum / float64(len(delays))
+	var variance float64
+	for _, d := range delays {
+		variance += (d - mean) * (d - mean)
+	}
+	stddev := math.Sqrt(variance / float64(len(delays)))
+	// 标准差与平均值之比越大越不稳定
+	return success / (1 + stddev/mean)
+}
+
+// RecordDelay 追加一次延迟测试结果并返回更新后的历史
+func RecordDelay(proxy *models.ProxieInfo, delay uint16) (*DelayHistory, error) {
+	key := (*models.ProxieDelayKey)(&proxy.Id).ToKey()
+	history := &DelayHistory{}
+	err := env.GetDB().Update(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err == nil {
+			if err := item.Value(func(val []byte) error {
+				return json.Unmarshal(val, history)
+			}); err != nil {
+				return err
+			}
+		} else if !errors.Is(err, badger.ErrKeyNotFound) {
+			return err
+		}
+		history.Samples = append(history.Samples, DelaySample{At: time.Now(), Delay: delay})
+		if len(history.Samples) > maxDelaySamples {
+			history.Samples = history.Samples[len(history.Samples)-maxDelaySamples:]
+		}
+		data, err := json.Marshal(history)
+		if err != nil {
+			return err
+		}
+		return txn.SetEntry(badger.NewEntry(key, data).WithTTL(delayHistoryTTL))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return history, nil
+}
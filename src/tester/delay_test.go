@@ -0,0 +1,34 @@
+package tester
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDelayHistory_Stability(t *testing.T) {
+	samples := func(delays ...uint16) *DelayHistory {
+		h := &DelayHistory{}
+		for _, d := range delays {
+			h.Samples = append(h.Samples, DelaySample{Delay: d})
+		}
+		return h
+	}
+	tests := []struct {
+		name    string
+		history *DelayHistory
+		want    float64
+	}{
+		{"nil", nil, 0},
+		{"all failed", samples(0, 0), 0},
+		{"steady", samples(100, 100, 100), 1},
+		{"half failed", samples(100, 0, 100, 0), 0.5},
+		{"jitter", samples(100, 300), 1 / 1.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.history.Stability(); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Stability() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
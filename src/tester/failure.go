@@ -0,0 +1,173 @@
+package tester
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/ocyss/sub-store-lab/src/env"
+	"github.com/ocyss/sub-store-lab/src/models"
+	"github.com/ocyss/sub-store-lab/src/utils"
+)
+
+type ErrorClass string
+
+const (
+	ErrorClassTimeout     ErrorClass = "timeout"      // 超时
+	ErrorClassDNS         ErrorClass = "dns"          // 域名解析失败
+	ErrorClassConn        ErrorClass = "conn"         // 连接被重置/拒绝
+	ErrorClassTLS         ErrorClass = "tls"          // TLS/证书错误
+	ErrorClassRateLimit   ErrorClass = "rate_limit"   // 被限流
+	ErrorClassServerError ErrorClass = "server_error" // 5xx
+	ErrorClassClientError ErrorClass = "client_error" // 4xx
+	ErrorClassUnknown     ErrorClass = "unknown"
+)
+
+// failureTTL 失败记录保留时间，需覆盖多个cron周期才能统计连续失败
+const failureTTL = time.Hour * 24 * 30
+
+// maxSkipRuns 隔离节点最多连续跳过的运行次数
+const maxSkipRuns = 8
+
+// Transient 是否为临时性失败，临时性失败会在本次运行中重试
+func (c ErrorClass) Transient() bool {
+	switch c {
+	case ErrorClassTimeout, ErrorClassDNS, ErrorClassConn, ErrorClassRateLimit, ErrorClassServerError:
+		return true
+	default:
+		return false
+	}
+}
+
+// StatusError 非预期的HTTP状态码
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("status %d", e.StatusCode)
+}
+
+// ClassifyError 对测试错误进行分类
+func ClassifyError(err error) ErrorClass {
+	var (
+		statusErr *StatusError
+		dnsErr    *net.DNSError
+		netErr    net.Error
+		tlsErr    *tls.RecordHeaderError
+		certErr   *tls.CertificateVerificationError
+		unknownCA x509.UnknownAuthorityError
+	)
+	switch {
+	case errors.As(err, &statusErr):
+		switch {
+		case statusErr.StatusCode == http.StatusTooManyRequests:
+			return ErrorClassRateLimit
+		case statusErr.StatusCode >= 500:
+			return ErrorClassServerError
+		default:
+			return ErrorClassClientError
+		}
+	case errors.As(err, &dnsErr):
+		return ErrorClassDNS
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorClassTimeout
+	case errors.As(err, &tlsErr), errors.As(err, &certErr), errors.As(err, &unknownCA):
+		return ErrorClassTLS
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return ErrorClassConn
+	default:
+		return ErrorClassUnknown
+	}
+}
+
+// FailureRecord 节点测试失败记录，测试成功后删除
+type FailureRecord struct {
+	Class            ErrorClass
+	Error            string
+	Attempts         int // 最后一次运行的尝试次数
+	ConsecutiveFails int // 连续失败的运行次数
+	Quarantined      bool
+	SkipRuns         int // 隔离中剩余跳过的运行次数
+	LastFailed       time.Time
+}
+
+func GetFailure(name models.ProxieTesterType, proxy *models.ProxieInfo) (*FailureRecord, error) {
+	key := models.ProxieFailKey{ProxieKey: proxy.Id, Type: name}
+	record, err := env.QueryDb[FailureRecord](key.ToKey())
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("tester[%s].GetFailure id: %s: %w", name, proxy.Id, err)
+	}
+	return &record, nil
+}
+
+func saveFailure(key models.ProxieFailKey, record *FailureRecord) error {
+	return env.GetDB().Update(func(txn *badger.Txn) error {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		return txn.SetEntry(badger.NewEntry(key.ToKey(), data).WithTTL(failureTTL))
+	})
+}
+
+func clearFailure(key models.ProxieFailKey) error {
+	return env.GetDB().Update(func(txn *badger.Txn) error {
+		return txn.Delete(key.ToKey())
+	})
+}
+
+// recordFailure 累计连续失败次数，超过阈值后隔离节点，隔离期间跳过的运行次数按指数增长
+func recordFailure(key models.ProxieFailKey, record *FailureRecord, conf *models.Conf, err error, attempts int) *FailureRecord {
+	if record == nil {
+		record = &FailureRecord{}
+	}
+	record.Class = ClassifyError(err)
+	record.Error = err.Error()
+	record.Attempts = attempts
+	record.ConsecutiveFails++
+	record.LastFailed = time.Now()
+	if conf.QuarantineAfter > 0 && record.ConsecutiveFails >= conf.QuarantineAfter {
+		record.Quarantined = true
+		record.SkipRuns = min(1<<min(record.ConsecutiveFails-conf.QuarantineAfter, 3), maxSkipRuns)
+	}
+	if err := saveFailure(key, record); err != nil {
+		slog.Error("failed to save failure", "key", key, "error", err)
+	}
+	return record
+}
+
+// runWithRetry 运行测试，临时性失败按指数退避重试
+func runWithRetry(t models.ProxieTester, proxy *models.ProxieInfo, proxie map[string]any) (val any, attempts int, err error) {
+	backoff := time.Second * 2
+	for attempts = 1; ; attempts++ {
+		var transport http.RoundTripper
+		transport, err = utils.CreateMihomoProxy(proxie)
+		if err != nil {
+			return nil, attempts, err
+		}
+		val, err = t.RunTest(proxy, transport)
+		if err == nil {
+			return val, attempts, nil
+		}
+		class := ClassifyError(err)
+		if !class.Transient() || attempts > proxy.Conf.RetryTimes {
+			return nil, attempts, err
+		}
+		slog.Debug("retry test", "proxie", proxy.Id, "class", class, "attempts", attempts, "backoff", backoff, "error", err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
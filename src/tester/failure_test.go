@@ -0,0 +1,109 @@
+package tester
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/ocyss/sub-store-lab/src/env"
+	"github.com/ocyss/sub-store-lab/src/models"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		want          ErrorClass
+		wantTransient bool
+	}{
+		{"deadline", fmt.Errorf("下载测试失败1: %w", context.DeadlineExceeded), ErrorClassTimeout, true},
+		{"dns", &net.DNSError{Err: "no such host", Name: "example.com"}, ErrorClassDNS, true},
+		{"reset", fmt.Errorf("read: %w", syscall.ECONNRESET), ErrorClassConn, true},
+		{"eof", fmt.Errorf("read: %w", io.EOF), ErrorClassConn, true},
+		{"429", fmt.Errorf("下载测试失败3: %w", &StatusError{StatusCode: 429}), ErrorClassRateLimit, true},
+		{"503", &StatusError{StatusCode: 503}, ErrorClassServerError, true},
+		{"404", &StatusError{StatusCode: 404}, ErrorClassClientError, false},
+		{"joined", errors.Join(errors.New("a"), fmt.Errorf("b: %w", context.DeadlineExceeded)), ErrorClassTimeout, true},
+		{"unknown", errors.New("IP风控值测试全部失败"), ErrorClassUnknown, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ClassifyError(tt.err)
+			if got != tt.want || got.Transient() != tt.wantTransient {
+				t.Errorf("ClassifyError() = %v (transient %v), want %v (transient %v)", got, got.Transient(), tt.want, tt.wantTransient)
+			}
+		})
+	}
+}
+
+// countTester 记录被测试的节点
+type countTester struct {
+	runs map[models.ProxieKey]int
+}
+
+func (c *countTester) Name() models.ProxieTesterType { return "Count" }
+func (c *countTester) Cron(*models.Conf) string      { return "0 3 * * *" }
+func (c *countTester) GetResult(*models.ProxieInfo) (any, error) {
+	return nil, nil
+}
+func (c *countTester) RunTest(proxy *models.ProxieInfo, _ http.RoundTripper) (any, error) {
+	c.runs[proxy.Id]++
+	return "ok", nil
+}
+
+func TestTaskFunc_QuarantinedTargeted(t *testing.T) {
+	if err := env.InitMemoryDB(); err != nil {
+		t.Fatalf("InitMemoryDB() error = %v", err)
+	}
+	defer env.CloseDB()
+	counter := &countTester{runs: make(map[models.ProxieKey]int)}
+	testers[counter.Name()] = counter
+	defer delete(testers, counter.Name())
+
+	conf := models.DefaultConf()
+	conf.Id = "conf"
+	quarantined := models.ProxieKey{ConfId: conf.Id, SubName: "A", Fingerprint: "quarantined"}
+	fresh := models.ProxieKey{ConfId: conf.Id, SubName: "A", Fingerprint: "new"}
+	err := env.GetDB().Update(func(txn *badger.Txn) error {
+		for _, key := range []models.ProxieKey{quarantined, fresh} {
+			data, _ := json.Marshal(map[string]any{
+				"name": key.Fingerprint, "type": "ss", "server": "127.0.0.1", "port": 8388, "cipher": "aes-128-gcm", "password": "p",
+			})
+			if err := txn.Set(key.ToKey(), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	failKey := models.ProxieFailKey{ProxieKey: quarantined, Type: counter.Name()}
+	if err := saveFailure(failKey, &FailureRecord{Quarantined: true, SkipRuns: 2, ConsecutiveFails: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 两个节点都没有结果，每次请求都会指定运行
+	taskFunc(&CronTask{
+		Key:          models.CronJobKey{ConfId: conf.Id, Type: counter.Name()},
+		Conf:         *conf,
+		FilterProxie: map[models.ProxieKey]struct{}{quarantined: {}, fresh: {}},
+	})
+	if counter.runs[quarantined] != 0 {
+		t.Errorf("quarantined proxie ran %d times, want 0", counter.runs[quarantined])
+	}
+	if counter.runs[fresh] != 1 {
+		t.Errorf("new proxie ran %d times, want 1", counter.runs[fresh])
+	}
+	failure, err := GetFailure(counter.Name(), &models.ProxieInfo{Id: quarantined})
+	if err != nil || failure == nil || failure.SkipRuns != 1 {
+		t.Errorf("failure = %+v, %v, want SkipRuns 1", failure, err)
+	}
+}
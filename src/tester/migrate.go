@@ -0,0 +1,164 @@
+package tester
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/ocyss/sub-store-lab/src/env"
+	"github.com/ocyss/sub-store-lab/src/models"
+)
+
+// fingerprintMigration 节点标识由名称改为连接参数指纹
+const fingerprintMigration = "proxie-fingerprint"
+
+type keyMove struct {
+	from, to  []byte
+	value     []byte
+	expiresAt uint64
+}
+
+// MigrateProxieKeys 将以节点名称为标识的 Proxie/ ProxieResult/ ProxieFail/ ProxieDelay/ 迁移到指纹，
+// 名称记录为别名，找不到对应节点的旧结果无法再被读取，直接删除，只执行一次
+func MigrateProxieKeys() error {
+	db := env.GetDB()
+	marker := models.MigrationKey{Name: fingerprintMigration}
+	if _, err := env.QueryDb[time.Time](marker.ToKey()); err == nil {
+		return nil
+	} else if !errors.Is(err, badger.ErrKeyNotFound) {
+		return fmt.Errorf("读取迁移记录失败: %w", err)
+	}
+
+	var (
+		moves        []keyMove
+		deletes      [][]byte
+		aliases      = make(map[models.ProxieKey][]string)
+		fingerprints = make(map[string]string) // conf::sub::name -> fingerprint
+	)
+	err := db.View(func(txn *badger.Txn) error {
+		// 节点数据中保存了连接参数，先计算各名称对应的指纹
+		err := iteratePrefix(txn, models.ProxieKeyPrefix, func(item *badger.Item, rest string) error {
+			parts := strings.SplitN(rest, "::", 3)
+			if len(parts) != 3 {
+				return nil
+			}
+			value, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			var proxie map[string]any
+			if err := json.Unmarshal(value, &proxie); err != nil {
+				deletes = append(deletes, item.KeyCopy(nil))
+				return nil
+			}
+			key := models.ProxieKey{ConfId: parts[0], SubName: parts[1], Fingerprint: models.ProxieFingerprint(proxie)}
+			if key.Fingerprint == parts[2] {
+				return nil
+			}
+			fingerprints[rest] = key.Fingerprint
+			aliases[key] = addAlias(aliases[key], parts[2])
+			moves = append(moves, keyMove{from: item.KeyCopy(nil), to: key.ToKey(), value: value, expiresAt: item.ExpiresAt()})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// 其余数据按名称找到指纹后移动，key末尾为测试类型的需先去掉类型
+		for _, prefix := range []string{models.ProxieResultKeyPrefix, models.ProxieFailKeyPrefix, models.ProxieDelayKeyPrefix} {
+			withType := prefix != models.ProxieDelayKeyPrefix
+			err := iteratePrefix(txn, prefix, func(item *badger.Item, rest string) error {
+				name, suffix := rest, ""
+				if withType {
+					i := strings.LastIndex(rest, "::")
+					if i < 0 {
+						return nil
+					}
+					name, suffix = rest[:i], rest[i:]
+				}
+				fingerprint, ok := fingerprints[name]
+				if !ok {
+					deletes = append(deletes, item.KeyCopy(nil))
+					return nil
+				}
+				value, err := item.ValueCopy(nil)
+				if err != nil {
+					return err
+				}
+				parts := strings.SplitN(name, "::", 3)
+				to := prefix + strings.Join([]string{parts[0], parts[1], fingerprint}, "::") + suffix
+				moves = append(moves, keyMove{from: item.KeyCopy(nil), to: []byte(to), value: value, expiresAt: item.ExpiresAt()})
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("读取待迁移数据失败: %w", err)
+	}
+
+	wb := db.NewWriteBatch()
+	defer wb.Cancel()
+	for _, move := range moves {
+		if err := wb.Delete(move.from); err != nil {
+			return err
+		}
+		entry := badger.NewEntry(move.to, move.value)
+		if move.expiresAt > 0 {
+			ttl := time.Until(time.Unix(int64(move.expiresAt), 0))
+			if ttl <= 0 {
+				continue
+			}
+			entry = entry.WithTTL(ttl)
+		}
+		if err := wb.SetEntry(entry); err != nil {
+			return err
+		}
+	}
+	for key, names := range aliases {
+		data, err := json.Marshal(names)
+		if err != nil {
+			return err
+		}
+		if err := wb.SetEntry(badger.NewEntry((*models.ProxieAliasKey)(&key).ToKey(), data).WithTTL(aliasTTL)); err != nil {
+			return err
+		}
+	}
+	for _, key := range deletes {
+		if err := wb.Delete(key); err != nil {
+			return err
+		}
+	}
+	data, err := json.Marshal(time.Now())
+	if err != nil {
+		return err
+	}
+	if err := wb.Set(marker.ToKey(), data); err != nil {
+		return err
+	}
+	if err := wb.Flush(); err != nil {
+		return fmt.Errorf("写入迁移数据失败: %w", err)
+	}
+	slog.Info("节点标识迁移完成", "migration", fingerprintMigration, "moved", len(moves), "deleted", len(deletes), "proxies", len(fingerprints))
+	return nil
+}
+
+// iteratePrefix 遍历前缀下的key，rest为去掉前缀后的部分
+func iteratePrefix(txn *badger.Txn, prefix string, fn func(item *badger.Item, rest string) error) error {
+	it := txn.NewIterator(badger.DefaultIteratorOptions)
+	defer it.Close()
+	for it.Seek([]byte(prefix)); it.ValidForPrefix([]byte(prefix)); it.Next() {
+		item := it.Item()
+		if err := fn(item, strings.TrimPrefix(string(item.Key()), prefix)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
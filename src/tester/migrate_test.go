@@ -0,0 +1,116 @@
+package tester
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/ocyss/sub-store-lab/src/env"
+	"github.com/ocyss/sub-store-lab/src/models"
+)
+
+func TestMigrateProxieKeys(t *testing.T) {
+	if err := env.InitMemoryDB(); err != nil {
+		t.Fatalf("InitMemoryDB() error = %v", err)
+	}
+	defer env.CloseDB()
+	db := env.GetDB()
+
+	proxie := map[string]any{"name": "🇭🇰 香港01", "type": "ss", "server": "hk.example.com", "port": 443, "password": "p"}
+	key := models.ProxieKey{ConfId: "c", SubName: "A", Fingerprint: models.ProxieFingerprint(proxie)}
+	data, err := json.Marshal(proxie)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 旧版本以名称作为标识
+	legacy := "c::A::🇭🇰 香港01"
+	err = db.Update(func(txn *badger.Txn) error {
+		entries := []*badger.Entry{
+			badger.NewEntry([]byte(models.ProxieKeyPrefix+legacy), data).WithTTL(time.Hour),
+			badger.NewEntry([]byte(models.ProxieResultKeyPrefix+legacy+"::Speed"), []byte(`"speed"`)).WithTTL(time.Hour),
+			badger.NewEntry([]byte(models.ProxieFailKeyPrefix+legacy+"::Purity"), []byte(`"fail"`)),
+			badger.NewEntry([]byte(models.ProxieDelayKeyPrefix+legacy), []byte(`"delay"`)),
+			// 找不到对应节点的结果
+			badger.NewEntry([]byte(models.ProxieResultKeyPrefix+"c::A::已删除::Speed"), []byte(`"orphan"`)),
+		}
+		for _, e := range entries {
+			if err := txn.SetEntry(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := MigrateProxieKeys(); err != nil {
+		t.Fatalf("MigrateProxieKeys() error = %v", err)
+	}
+
+	moved := []struct {
+		name  string
+		key   []byte
+		value string
+		ttl   bool
+	}{
+		{"proxie", key.ToKey(), string(data), true},
+		{"result", (&models.ProxieResultKey{ProxieKey: key, Type: "Speed"}).ToKey(), `"speed"`, true},
+		{"fail", (&models.ProxieFailKey{ProxieKey: key, Type: "Purity"}).ToKey(), `"fail"`, false},
+		{"delay", (*models.ProxieDelayKey)(&key).ToKey(), `"delay"`, false},
+	}
+	err = db.View(func(txn *badger.Txn) error {
+		for _, m := range moved {
+			item, err := txn.Get(m.key)
+			if err != nil {
+				t.Errorf("%s: Get(%s) error = %v", m.name, m.key, err)
+				continue
+			}
+			if value, _ := item.ValueCopy(nil); string(value) != m.value {
+				t.Errorf("%s: value = %s, want %s", m.name, value, m.value)
+			}
+			if hasTTL := item.ExpiresAt() > 0; hasTTL != m.ttl {
+				t.Errorf("%s: ExpiresAt = %d, want ttl %v", m.name, item.ExpiresAt(), m.ttl)
+			} else if m.ttl && time.Until(time.Unix(int64(item.ExpiresAt()), 0)) > time.Hour {
+				t.Errorf("%s: ttl extended to %s", m.name, time.Unix(int64(item.ExpiresAt()), 0))
+			}
+		}
+		for _, k := range []string{
+			models.ProxieKeyPrefix + legacy,
+			models.ProxieResultKeyPrefix + legacy + "::Speed",
+			models.ProxieFailKeyPrefix + legacy + "::Purity",
+			models.ProxieDelayKeyPrefix + legacy,
+			models.ProxieResultKeyPrefix + "c::A::已删除::Speed",
+		} {
+			if _, err := txn.Get([]byte(k)); !errors.Is(err, badger.ErrKeyNotFound) {
+				t.Errorf("Get(%s) error = %v, want deleted", k, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names, err := env.QueryDb[[]string]((*models.ProxieAliasKey)(&key).ToKey()); err != nil || len(names) != 1 || names[0] != "🇭🇰 香港01" {
+		t.Errorf("alias = %v, %v, want [🇭🇰 香港01]", names, err)
+	}
+
+	// 已迁移过的数据库不再执行
+	stale := []byte(models.ProxieResultKeyPrefix + "c::A::另一个::Speed")
+	if err := db.Update(func(txn *badger.Txn) error { return txn.Set(stale, []byte(`"stale"`)) }); err != nil {
+		t.Fatal(err)
+	}
+	if err := MigrateProxieKeys(); err != nil {
+		t.Fatalf("second MigrateProxieKeys() error = %v", err)
+	}
+	err = db.View(func(txn *badger.Txn) error {
+		_, err := txn.Get(stale)
+		return err
+	})
+	if err != nil {
+		t.Errorf("second run touched %s: %v", stale, err)
+	}
+}
@@ -2,6 +2,7 @@ package tester
 
 import (
 	"fmt"
+	"log/slog"
 	"net/http"
 	"runtime/debug"
 	"time"
@@ -23,7 +24,25 @@ func (p *Purity) Cron(conf *models.Conf) string {
 }
 
 func (p *Purity) GetResult(proxy *models.ProxieInfo) (any, error) {
-	return getResult[PurityResult](p.Name(), proxy)
+	result, err := getResult[PurityResult](p.Name(), proxy)
+	if err != nil || result == nil {
+		return nil, err
+	}
+	// 评分配置变化后使用缓存的出口IP检测结果重新合并，无需重新检测，
+	// 合并结果写回并保留原有效期，避免每次读取都重新合并
+	if result.ScoringHash != purity.ScoringHash(proxy.Conf) {
+		if merged, ok := purity.Remerge(proxy.Conf, (*purity.PurityResult)(result)); ok {
+			result = (*PurityResult)(merged)
+			if err := updateResult(p.Name(), proxy, result); err != nil {
+				slog.Warn("failed to save remerged purity result", "id", proxy.Id, "error", err)
+			}
+		}
+	}
+	// 图标不影响检测结果，按当前conf重新生成
+	result.PurityIcon = purity.GetPurityIcon(proxy.Conf, result.RiskScore, &result.Confidence)
+	result.TypeIcon = purity.GetTypeIcon(proxy.Conf, result.UsageType)
+	result.Stale = isStale(result.LastUpdated, ResultTTL(proxy.Conf, p))
+	return *result, nil
 }
 
 func (p *Purity) RunTest(proxy *models.ProxieInfo, transport http.RoundTripper) (_ any, err error) {
@@ -34,6 +53,7 @@ func (p *Purity) RunTest(proxy *models.ProxieInfo, transport http.RoundTripper)
 		}
 	}()
 	detector := purity.NewIPPurityDetector(proxy.Conf, 10*time.Second)
+	detector.Scope = string(proxy.Id.ToKey())
 	ipInfo, err := detector.DetectIP(transport)
 	if err != nil {
 		return nil, err
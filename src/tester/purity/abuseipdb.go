@@ -80,24 +80,32 @@ func (d *AbuseIPDBDetector) Detect(client *resty.Client, ip string) (*proxiePuri
 	}
 
 	var abuseResp AbuseIPDBResponse
-	_, err := client.R().
-		SetQueryParams(map[string]string{
-			"ipAddress":    ip,
-			"maxAgeInDays": "90",
-			"verbose":      "true",
-		}).
-		SetHeader("Key", d.APIKey.Get()).
-		SetHeader("Accept", "application/json").
-		SetResult(&abuseResp).
-		Get(IPBlacklistAPI)
+	_, resp, err := d.APIKey.Request(func(key string) (*resty.Response, error) {
+		abuseResp = AbuseIPDBResponse{}
+		return client.R().
+			SetQueryParams(map[string]string{
+				"ipAddress":    ip,
+				"maxAgeInDays": "90",
+				"verbose":      "true",
+			}).
+			SetHeader("Key", key).
+			SetHeader("Accept", "application/json").
+			SetResult(&abuseResp).
+			Get(IPBlacklistAPI)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("请求AbuseIPDB API失败: %w", err)
 	}
 
+	if resp.StatusCode() != 200 {
+		return nil, fmt.Errorf("AbuseIPDB API返回非200状态码: %d", resp.StatusCode())
+	}
+
 	result := &proxiePurity{
 		IP:         lo.ToPtr(abuseResp.Data.IPAddress),
 		Country:    lo.ToPtr(abuseResp.Data.CountryCode),
 		RiskScore:  lo.ToPtr(abuseResp.Data.AbuseConfidenceScore),
+		Org:        lo.EmptyableToPtr(abuseResp.Data.Isp),
 		DetectName: lo.ToPtr(d.Name()),
 	}
 
@@ -0,0 +1,353 @@
+package purity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/ocyss/sub-store-lab/src/env"
+	"github.com/ocyss/sub-store-lab/src/models"
+	"github.com/samber/lo"
+	"resty.dev/v3"
+)
+
+// ErrKeyExhausted 全部API密钥均处于冷却中
+var ErrKeyExhausted = errors.New("API密钥配额已耗尽")
+
+const (
+	rateLimitCooldown = time.Hour      // 429 未返回重置时间时的冷却时长
+	quotaCooldown     = time.Hour * 24 // 402/配额耗尽 未返回重置时间时的冷却时长
+	keyStateTTL       = time.Hour * 24 * 40
+)
+
+// KeyState 单个API密钥的用量统计，持久化到数据库
+type KeyState struct {
+	Masked        string
+	Used          int64 // 请求次数
+	Errors        int64 // 失败次数
+	RateLimited   int64 // 429/402 次数
+	Limit         *int  // 服务端返回的配额上限
+	Remaining     *int  // 服务端返回的剩余配额
+	ResetAt       time.Time
+	CooldownUntil time.Time
+	LastUsed      time.Time
+	LastError     string
+
+	key string
+	id  string
+}
+
+func (k *KeyState) available(now time.Time) bool {
+	return now.After(k.CooldownUntil)
+}
+
+type ApiKey struct {
+	name  string
+	raw   string
+	keys  []*KeyState
+	index int
+	mu    sync.Mutex
+}
+
+var (
+	apiKeys   = make(map[string]*ApiKey)
+	apiKeysMu sync.Mutex
+)
+
+// NewApiKey 传入一个以 "," 分割的字符串创建 ApiKey 实例，同名实例全局共享以保留轮换位置和用量
+func NewApiKey(name string, keyStr string) *ApiKey {
+	apiKeysMu.Lock()
+	defer apiKeysMu.Unlock()
+	if a, ok := apiKeys[name]; ok && a.raw == keyStr {
+		return a
+	}
+	a := &ApiKey{
+		name: name,
+		raw:  keyStr,
+	}
+	for key := range strings.SplitSeq(keyStr, ",") {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		a.keys = append(a.keys, a.loadState(key))
+	}
+	apiKeys[name] = a
+	return a
+}
+
+func (a *ApiKey) loadState(key string) *KeyState {
+	sum := sha256.Sum256([]byte(key))
+	id := hex.EncodeToString(sum[:6])
+	state := &KeyState{Masked: maskKey(key)}
+	if db := env.GetDB(); db != nil {
+		dbKey := models.ApiKeyStateKey{Detector: a.name, KeyId: id}
+		if saved, err := env.QueryDb[KeyState](dbKey.ToKey()); err == nil {
+			state = &saved
+		} else if !errors.Is(err, badger.ErrKeyNotFound) {
+			slog.Warn("failed to load api key state", "detector", a.name, "key", maskKey(key), "error", err)
+		}
+	}
+	state.key = key
+	state.id = id
+	return state
+}
+
+func (a *ApiKey) saveState(state *KeyState) {
+	db := env.GetDB()
+	if db == nil {
+		return
+	}
+	dbKey := models.ApiKeyStateKey{Detector: a.name, KeyId: state.id}
+	err := db.Update(func(txn *badger.Txn) error {
+		data, err := json.Marshal(state)
+		if err != nil {
+			return err
+		}
+		return txn.SetEntry(badger.NewEntry(dbKey.ToKey(), data).WithTTL(keyStateTTL))
+	})
+	if err != nil {
+		slog.Warn("failed to save api key state", "detector", a.name, "key", state.Masked, "error", err)
+	}
+}
+
+// Get 返回下一个未处于冷却中的 key（循环使用），全部冷却时返回空字符串
+func (a *ApiKey) Get() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	for range a.keys {
+		state := a.keys[a.index]
+		a.index = (a.index + 1) % len(a.keys)
+		if state.available(now) {
+			// 冷却结束后服务端记录的配额已重置，不再按旧的剩余配额判断
+			if !state.CooldownUntil.IsZero() {
+				state.CooldownUntil = time.Time{}
+				state.Remaining = nil
+				state.ResetAt = time.Time{}
+			}
+			return state.key
+		}
+	}
+	return ""
+}
+
+// Request 使用可用的key发送请求并记录结果，429/402 时换下一个key重试，
+// 返回最后使用的key，全部key均处于冷却时返回 ErrKeyExhausted
+func (a *ApiKey) Request(send func(key string) (*resty.Response, error)) (string, *resty.Response, error) {
+	var (
+		key  string
+		resp *resty.Response
+		err  error
+	)
+	for range a.keys {
+		next := a.Get()
+		if next == "" {
+			break
+		}
+		key = next
+		resp, err = send(key)
+		a.Report(key, resp, err)
+		if err != nil || resp == nil || !quotaStatus(resp.StatusCode()) {
+			return key, resp, err
+		}
+	}
+	if key == "" {
+		return "", nil, ErrKeyExhausted
+	}
+	return key, resp, err
+}
+
+// quotaStatus 限流或配额耗尽的状态码，换key后可能成功
+func quotaStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code == http.StatusPaymentRequired
+}
+
+func (a *ApiKey) state(key string) *KeyState {
+	for _, state := range a.keys {
+		if state.key == key {
+			return state
+		}
+	}
+	return nil
+}
+
+// Report 记录一次请求结果，解析限流响应头，429/402 或本次响应的剩余配额为0时冷却该key直到重置时间
+func (a *ApiKey) Report(key string, resp *resty.Response, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	state := a.state(key)
+	if state == nil {
+		return
+	}
+	now := time.Now()
+	state.Used++
+	state.LastUsed = now
+	if err != nil {
+		state.Errors++
+		state.LastError = err.Error()
+	}
+	if resp != nil {
+		header := resp.Header()
+		code := resp.StatusCode()
+		if v, ok := headerInt(header, "X-RateLimit-Limit", "X-Ratelimit-Limit-Requests"); ok {
+			state.Limit = &v
+		}
+		remaining, hasRemaining := headerInt(header, "X-RateLimit-Remaining", "X-Ratelimit-Remaining-Requests")
+		reset, hasReset := headerReset(header, now)
+		switch {
+		case hasRemaining:
+			state.Remaining = &remaining
+		case code >= 200 && code < 300:
+			// 请求成功且未返回剩余配额，之前记录的配额已失效
+			state.Remaining = nil
+		}
+		switch {
+		case hasReset:
+			state.ResetAt = reset
+		case code >= 200 && code < 300:
+			state.ResetAt = time.Time{}
+		}
+		switch {
+		case code == http.StatusTooManyRequests:
+			state.RateLimited++
+			state.LastError = resp.Status()
+			state.CooldownUntil = a.cooldownUntil(state, now, rateLimitCooldown)
+		case code == http.StatusPaymentRequired:
+			state.RateLimited++
+			state.LastError = resp.Status()
+			state.CooldownUntil = a.cooldownUntil(state, now, quotaCooldown)
+		case code >= 400:
+			state.Errors++
+			state.LastError = resp.Status()
+		case hasRemaining && remaining <= 0:
+			// 仅按本次响应返回的剩余配额判断，旧记录可能已重置
+			state.CooldownUntil = a.cooldownUntil(state, now, quotaCooldown)
+		}
+	}
+	a.saveState(state)
+}
+
+// Exhausted 标记key配额耗尽，用于状态码为200但响应内容提示配额不足的接口
+func (a *ApiKey) Exhausted(key string, message string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	state := a.state(key)
+	if state == nil {
+		return
+	}
+	now := time.Now()
+	state.RateLimited++
+	state.LastError = message
+	state.Remaining = new(int)
+	state.CooldownUntil = a.cooldownUntil(state, now, quotaCooldown)
+	a.saveState(state)
+}
+
+func (a *ApiKey) cooldownUntil(state *KeyState, now time.Time, fallback time.Duration) time.Time {
+	until := now.Add(fallback)
+	if state.ResetAt.After(now) {
+		until = state.ResetAt
+	}
+	slog.Warn("api key cooldown", "detector", a.name, "key", state.Masked, "until", until)
+	return until
+}
+
+// QuotaStat 检测器的密钥用量汇总
+type QuotaStat struct {
+	Detector  string
+	Available int  // 未处于冷却中的key数量
+	Remaining *int // 服务端返回的剩余配额之和，均未返回时为空
+	Keys      []KeyState
+}
+
+// GetQuotaStats 返回全部检测器的密钥用量
+func GetQuotaStats() []QuotaStat {
+	apiKeysMu.Lock()
+	defer apiKeysMu.Unlock()
+	now := time.Now()
+	stats := make([]QuotaStat, 0, len(apiKeys))
+	for name, a := range apiKeys {
+		a.mu.Lock()
+		stat := QuotaStat{Detector: name}
+		for _, state := range a.keys {
+			if state.available(now) {
+				stat.Available++
+			}
+			if state.Remaining != nil {
+				stat.Remaining = lo.ToPtr(lo.FromPtr(stat.Remaining) + *state.Remaining)
+			}
+			stat.Keys = append(stat.Keys, *state)
+		}
+		a.mu.Unlock()
+		stats = append(stats, stat)
+	}
+	slices.SortFunc(stats, func(a, b QuotaStat) int {
+		return strings.Compare(a.Detector, b.Detector)
+	})
+	return stats
+}
+
+// knownKeys 返回全部已配置的密钥，用于录制时脱敏
+func knownKeys() []string {
+	apiKeysMu.Lock()
+	defer apiKeysMu.Unlock()
+	keys := make([]string, 0)
+	for _, a := range apiKeys {
+		for _, state := range a.keys {
+			keys = append(keys, state.key)
+			// Scamalytics 等 "用户名:密钥" 格式，URL中只出现密钥部分
+			if _, secret, ok := strings.Cut(state.key, ":"); ok {
+				keys = append(keys, secret)
+			}
+		}
+	}
+	return keys
+}
+
+func maskKey(key string) string {
+	if len(key) <= 8 {
+		return strings.Repeat("*", len(key))
+	}
+	return key[:4] + "****" + key[len(key)-4:]
+}
+
+func headerInt(header http.Header, names ...string) (int, bool) {
+	for _, name := range names {
+		if v := header.Get(name); v != "" {
+			if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+				return n, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// headerReset 解析重置时间，支持 Retry-After(秒/HTTP日期) 及 X-RateLimit-Reset(unix时间戳/秒数)
+func headerReset(header http.Header, now time.Time) (time.Time, bool) {
+	if v := header.Get("Retry-After"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			return now.Add(time.Duration(n) * time.Second), true
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return t, true
+		}
+	}
+	if n, ok := headerInt(header, "X-RateLimit-Reset"); ok {
+		if n > 1_000_000_000 {
+			return time.Unix(int64(n), 0), true
+		}
+		return now.Add(time.Duration(n) * time.Second), true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,150 @@
+package purity
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"resty.dev/v3"
+)
+
+func testResponse(code int, header map[string]string) *resty.Response {
+	raw := &http.Response{StatusCode: code, Status: http.StatusText(code), Header: make(http.Header)}
+	for k, v := range header {
+		raw.Header.Set(k, v)
+	}
+	return &resty.Response{RawResponse: raw}
+}
+
+func TestApiKey_Get(t *testing.T) {
+	a := NewApiKey(t.Name(), "a, b,,c")
+	var got []string
+	for range 4 {
+		got = append(got, a.Get())
+	}
+	if want := []string{"a", "b", "c", "a"}; !slices.Equal(got, want) {
+		t.Errorf("Get() = %v, want %v", got, want)
+	}
+	if NewApiKey(t.Name(), "a, b,,c") != a {
+		t.Error("NewApiKey() with same name and keys should share the instance")
+	}
+}
+
+func TestApiKey_Report(t *testing.T) {
+	tests := []struct {
+		name     string
+		resp     *resty.Response
+		cooldown time.Duration // 0 表示不冷却
+	}{
+		{"ok", testResponse(http.StatusOK, nil), 0},
+		{"remaining", testResponse(http.StatusOK, map[string]string{"X-RateLimit-Remaining": "3"}), 0},
+		{"remaining zero", testResponse(http.StatusOK, map[string]string{"X-RateLimit-Remaining": "0", "Retry-After": "60"}), time.Minute},
+		{"too many requests", testResponse(http.StatusTooManyRequests, nil), rateLimitCooldown},
+		{"payment required", testResponse(http.StatusPaymentRequired, nil), quotaCooldown},
+		{"retry after", testResponse(http.StatusTooManyRequests, map[string]string{"Retry-After": "120"}), 2 * time.Minute},
+		{"server error", testResponse(http.StatusInternalServerError, nil), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewApiKey(t.Name(), "k")
+			now := time.Now()
+			a.Report(a.Get(), tt.resp, nil)
+			state := a.state("k")
+			if tt.cooldown == 0 {
+				if !state.available(now) {
+					t.Errorf("CooldownUntil = %v, want available", state.CooldownUntil)
+				}
+				return
+			}
+			if d := state.CooldownUntil.Sub(now); d < tt.cooldown-time.Second || d > tt.cooldown+time.Second {
+				t.Errorf("cooldown = %v, want %v", d, tt.cooldown)
+			}
+			if a.Get() != "" {
+				t.Error("Get() should skip the key in cooldown")
+			}
+		})
+	}
+}
+
+func TestApiKey_ExhaustedRecovers(t *testing.T) {
+	a := NewApiKey(t.Name(), "k")
+	a.Exhausted("k", "quota exceeded")
+	if a.Get() != "" {
+		t.Fatal("Get() should skip an exhausted key")
+	}
+
+	// 冷却结束后恢复使用，未返回限流响应头的成功请求不再触发冷却
+	a.state("k").CooldownUntil = time.Now().Add(-time.Second)
+	if a.Get() != "k" {
+		t.Fatal("Get() should return the key after cooldown")
+	}
+	if state := a.state("k"); state.Remaining != nil || !state.ResetAt.IsZero() {
+		t.Errorf("state after cooldown = %+v, want Remaining and ResetAt cleared", state)
+	}
+	a.Report("k", testResponse(http.StatusOK, nil), nil)
+	if a.Get() != "k" {
+		t.Errorf("Get() after successful request = empty, CooldownUntil %v", a.state("k").CooldownUntil)
+	}
+
+	// 数据库中保存的旧剩余配额，成功请求后清除
+	a.state("k").Remaining = new(int)
+	a.Report("k", testResponse(http.StatusOK, nil), nil)
+	if state := a.state("k"); state.Remaining != nil || !state.available(time.Now()) {
+		t.Errorf("state = %+v, want Remaining cleared and available", state)
+	}
+}
+
+func TestApiKey_Request(t *testing.T) {
+	t.Run("rotate on quota", func(t *testing.T) {
+		a := NewApiKey(t.Name(), "a,b,c")
+		var sent []string
+		key, resp, err := a.Request(func(key string) (*resty.Response, error) {
+			sent = append(sent, key)
+			if key == "a" {
+				return testResponse(http.StatusTooManyRequests, nil), nil
+			}
+			if key == "b" {
+				return testResponse(http.StatusPaymentRequired, nil), nil
+			}
+			return testResponse(http.StatusOK, nil), nil
+		})
+		if err != nil || key != "c" || resp.StatusCode() != http.StatusOK {
+			t.Fatalf("Request() = %s, %v, %v, want c, 200", key, resp, err)
+		}
+		if want := []string{"a", "b", "c"}; !slices.Equal(sent, want) {
+			t.Errorf("sent = %v, want %v", sent, want)
+		}
+	})
+
+	t.Run("no rotation on other errors", func(t *testing.T) {
+		a := NewApiKey(t.Name(), "a,b")
+		calls := 0
+		_, resp, err := a.Request(func(string) (*resty.Response, error) {
+			calls++
+			return testResponse(http.StatusInternalServerError, nil), nil
+		})
+		if err != nil || resp.StatusCode() != http.StatusInternalServerError || calls != 1 {
+			t.Errorf("Request() = %v, %v after %d calls, want 500 after 1 call", resp, err, calls)
+		}
+	})
+
+	t.Run("all limited", func(t *testing.T) {
+		a := NewApiKey(t.Name(), "a,b")
+		calls := 0
+		_, resp, err := a.Request(func(string) (*resty.Response, error) {
+			calls++
+			return testResponse(http.StatusTooManyRequests, nil), nil
+		})
+		if err != nil || resp.StatusCode() != http.StatusTooManyRequests || calls != 2 {
+			t.Errorf("Request() = %v, %v after %d calls, want 429 after 2 calls", resp, err, calls)
+		}
+		if _, _, err := a.Request(func(string) (*resty.Response, error) {
+			t.Error("send called with all keys in cooldown")
+			return nil, nil
+		}); !errors.Is(err, ErrKeyExhausted) {
+			t.Errorf("Request() error = %v, want %v", err, ErrKeyExhausted)
+		}
+	})
+}
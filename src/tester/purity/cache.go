@@ -0,0 +1,203 @@
+package purity
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/ocyss/sub-store-lab/src/env"
+	"github.com/ocyss/sub-store-lab/src/models"
+	"github.com/samber/lo"
+	"golang.org/x/sync/singleflight"
+)
+
+// IPVerdict 单个出口IP的各检测器原始结果，按IP缓存，合并由各conf自行完成
+type IPVerdict struct {
+	IP         string
+	Results    []*proxiePurity
+	DetectedAt time.Time
+	Partial    bool // 部分检测器失败
+}
+
+// partialVerdictTTL 部分检测器失败的结果缓存时长上限，便于失败的检测器尽快重试
+const partialVerdictTTL = time.Hour
+
+// ipDetectGroup 同一出口IP的并发检测只请求一次
+var ipDetectGroup singleflight.Group
+
+func getIPVerdict(ip string) (*IPVerdict, error) {
+	key := models.IPPurityKey{IP: ip}
+	verdict, err := env.QueryDb[IPVerdict](key.ToKey())
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &verdict, nil
+}
+
+func saveIPVerdict(verdict *IPVerdict) error {
+	key := models.IPPurityKey{IP: verdict.IP}
+	ttl := env.Conf.IPCacheTTL
+	if verdict.Partial {
+		ttl = min(ttl, partialVerdictTTL)
+	}
+	return env.GetDB().Update(func(txn *badger.Txn) error {
+		data, err := json.Marshal(verdict)
+		if err != nil {
+			return err
+		}
+		return txn.SetEntry(badger.NewEntry(key.ToKey(), data).WithTTL(ttl))
+	})
+}
+
+// detectIPVerdict 优先使用缓存的IP检测结果，缓存不存在时调用全部检测器，
+// 并发请求共享的检测结果同样是本次检测得到的，不视为缓存命中
+func (d *IPPurityDetector) detectIPVerdict(ip string) (verdict *IPVerdict, cached bool, err error) {
+	if env.Conf.IPCacheTTL <= 0 {
+		verdict, err = d.runDetectors(ip)
+		return verdict, false, err
+	}
+	if verdict, err := getIPVerdict(ip); err != nil {
+		slog.Warn("failed to get ip verdict", "ip", ip, "error", err)
+	} else if verdict != nil {
+		return verdict, true, nil
+	}
+	v, err, _ := ipDetectGroup.Do(ip, func() (any, error) {
+		verdict, err := d.runDetectors(ip)
+		if err != nil {
+			return nil, err
+		}
+		if err := saveIPVerdict(verdict); err != nil {
+			slog.Warn("failed to save ip verdict", "ip", ip, "error", err)
+		}
+		return verdict, nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return v.(*IPVerdict), false, nil
+}
+
+// mergeVerdict 按conf的评分配置合并出口IP的各检测器结果，并应用自定义分类规则
+func mergeVerdict(conf *models.Conf, verdict *IPVerdict) *PurityResult {
+	merged := MergeIPInfo(conf, verdict.Results)
+	if merged.Error == nil {
+		applyRules(conf, merged, verdict.IP, verdict.Results)
+		merged.PurityIcon = GetPurityIcon(conf, merged.RiskScore, &merged.Confidence)
+		merged.TypeIcon = GetTypeIcon(conf, merged.UsageType)
+	}
+	merged.VerdictAt = verdict.DetectedAt
+	merged.ScoringHash = ScoringHash(conf)
+	return merged
+}
+
+// ScoringHash 合并结果所依赖的评分配置摘要，不确定判断仅影响图标，读取时按当前conf计算，不计入摘要
+func ScoringHash(conf *models.Conf) string {
+	scoring := conf.Scoring
+	scoring.MinAgreement, scoring.MaxSpread = 0, 0
+	return models.HashOf(scoring)
+}
+
+// Remerge 评分配置变化后，使用缓存的出口IP检测结果按当前配置重新合并，
+// 缓存已过期时返回false，继续使用原结果直到下次测试
+func Remerge(conf *models.Conf, result *PurityResult) (*PurityResult, bool) {
+	if result.IP == nil || env.GetDB() == nil {
+		return nil, false
+	}
+	verdict, err := getIPVerdict(*result.IP)
+	if err != nil {
+		slog.Warn("failed to get ip verdict", "ip", *result.IP, "error", err)
+		return nil, false
+	}
+	if verdict == nil {
+		return nil, false
+	}
+	merged := mergeVerdict(conf, verdict)
+	if merged.Error != nil {
+		return nil, false
+	}
+	merged.LastUpdated = result.LastUpdated
+	merged.Cached = true
+	return merged, true
+}
+
+// ExitNode 共享出口IP的节点
+type ExitNode struct {
+	ConfId      string
+	SubName     string
+	Fingerprint string
+	ProxieName  string   // 最近使用的名称
+	Aliases     []string // 曾用名称，最近使用的在前
+}
+
+// Exit 出口IP及使用该出口的节点
+type Exit struct {
+	IP         string
+	Country    *string
+	UsageType  *UsageType
+	RiskScore  *int
+	DetectedAt time.Time
+	Shared     bool
+	Nodes      []ExitNode
+}
+
+// GetExits 汇总节点纯净度结果，按出口IP分组，prefix为结果key前缀
+func GetExits(prefix []byte, testerType models.ProxieTesterType) ([]*Exit, error) {
+	exits := make(map[string]*Exit)
+	order := make([]string, 0)
+	err := env.QueryDbPrefix(func(txn *badger.Txn, k []byte, v PurityResult) error {
+		var key models.ProxieResultKey
+		if err := key.FromKey(k); err != nil || key.Type != testerType || v.IP == nil {
+			return nil
+		}
+		exit, ok := exits[*v.IP]
+		if !ok {
+			exit = &Exit{
+				IP:         *v.IP,
+				Country:    v.Country,
+				UsageType:  v.UsageType,
+				RiskScore:  v.RiskScore,
+				DetectedAt: v.VerdictAt,
+			}
+			exits[*v.IP] = exit
+			order = append(order, *v.IP)
+		}
+		aliases := proxieAliases(txn, key.ProxieKey)
+		exit.Nodes = append(exit.Nodes, ExitNode{
+			ConfId:      key.ConfId,
+			SubName:     key.SubName,
+			Fingerprint: key.Fingerprint,
+			ProxieName:  lo.FirstOrEmpty(aliases),
+			Aliases:     aliases,
+		})
+		exit.Shared = len(exit.Nodes) > 1
+		return nil
+	}, prefix, false)
+	if err != nil {
+		return nil, fmt.Errorf("GetExits: %w", err)
+	}
+	result := make([]*Exit, 0, len(order))
+	for _, ip := range order {
+		result = append(result, exits[ip])
+	}
+	return result, nil
+}
+
+// proxieAliases 读取节点指纹对应的名称
+func proxieAliases(txn *badger.Txn, key models.ProxieKey) []string {
+	item, err := txn.Get((*models.ProxieAliasKey)(&key).ToKey())
+	if err != nil {
+		return nil
+	}
+	var aliases []string
+	if err := item.Value(func(val []byte) error {
+		return json.Unmarshal(val, &aliases)
+	}); err != nil {
+		return nil
+	}
+	return aliases
+}
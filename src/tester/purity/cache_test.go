@@ -0,0 +1,166 @@
+package purity
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/ocyss/sub-store-lab/src/env"
+	"github.com/ocyss/sub-store-lab/src/models"
+	"github.com/samber/lo"
+	"resty.dev/v3"
+)
+
+func TestRemerge(t *testing.T) {
+	if err := env.InitMemoryDB(); err != nil {
+		t.Fatalf("InitMemoryDB() error = %v", err)
+	}
+	defer env.CloseDB()
+	defer func(ttl time.Duration) { env.Conf.IPCacheTTL = ttl }(env.Conf.IPCacheTTL)
+	env.Conf.IPCacheTTL = time.Hour
+
+	verdict := &IPVerdict{
+		IP: "1.2.3.4",
+		Results: []*proxiePurity{
+			{DetectName: lo.ToPtr("A"), IP: lo.ToPtr("1.2.3.4"), RiskScore: lo.ToPtr(80), Country: lo.ToPtr("US")},
+			{DetectName: lo.ToPtr("B"), IP: lo.ToPtr("1.2.3.4"), RiskScore: lo.ToPtr(20), Country: lo.ToPtr("US")},
+		},
+		DetectedAt: time.Now().Add(-time.Hour),
+	}
+	if err := saveIPVerdict(verdict); err != nil {
+		t.Fatalf("saveIPVerdict() error = %v", err)
+	}
+
+	conf := models.DefaultConf()
+	stored := mergeVerdict(conf, verdict)
+	stored.LastUpdated = time.Now().Add(-2 * time.Hour)
+	if *stored.RiskScore != 50 {
+		t.Fatalf("RiskScore = %d, want 50", *stored.RiskScore)
+	}
+
+	conf.Scoring.Detectors = map[string]float64{"B": 3}
+	if stored.ScoringHash == ScoringHash(conf) {
+		t.Fatal("ScoringHash should change with detector weights")
+	}
+	merged, ok := Remerge(conf, stored)
+	if !ok {
+		t.Fatal("Remerge() = false, want true")
+	}
+	if *merged.RiskScore != 35 { // (80+20*3)/4
+		t.Errorf("RiskScore = %d, want 35", *merged.RiskScore)
+	}
+	if merged.ScoringHash != ScoringHash(conf) || !merged.LastUpdated.Equal(stored.LastUpdated) {
+		t.Errorf("Remerge() hash/LastUpdated not carried over: %+v", merged)
+	}
+
+	// 不确定判断的参数不影响合并结果
+	conf.Scoring.MaxSpread = 10
+	if merged.ScoringHash != ScoringHash(conf) {
+		t.Error("ScoringHash should ignore max_spread")
+	}
+
+	// 出口IP缓存过期时保留原结果
+	stored.IP = lo.ToPtr("5.6.7.8")
+	if _, ok := Remerge(conf, stored); ok {
+		t.Error("Remerge() without cached verdict = true, want false")
+	}
+}
+
+// stubDetector 返回固定结果的检测器，gate 不为空时等待放行
+type stubDetector struct {
+	name  string
+	err   error
+	calls atomic.Int32
+	start chan struct{}
+	gate  chan struct{}
+}
+
+func (s *stubDetector) Name() string { return s.name }
+
+func (s *stubDetector) Detect(_ *resty.Client, ip string) (*proxiePurity, error) {
+	s.calls.Add(1)
+	if s.gate != nil {
+		s.start <- struct{}{}
+		<-s.gate
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &proxiePurity{DetectName: lo.ToPtr(s.name), IP: lo.ToPtr(ip), RiskScore: lo.ToPtr(10), Country: lo.ToPtr("US")}, nil
+}
+
+func TestDetectIPVerdict(t *testing.T) {
+	if err := env.InitMemoryDB(); err != nil {
+		t.Fatalf("InitMemoryDB() error = %v", err)
+	}
+	defer env.CloseDB()
+	defer func(ttl time.Duration) { env.Conf.IPCacheTTL = ttl }(env.Conf.IPCacheTTL)
+	env.Conf.IPCacheTTL = 24 * time.Hour
+
+	cacheTTL := func(ip string) time.Duration {
+		var ttl time.Duration
+		err := env.GetDB().View(func(txn *badger.Txn) error {
+			item, err := txn.Get((&models.IPPurityKey{IP: ip}).ToKey())
+			if err != nil {
+				return err
+			}
+			ttl = time.Until(time.Unix(int64(item.ExpiresAt()), 0))
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("cached verdict of %s: %v", ip, err)
+		}
+		return ttl
+	}
+	newDetector := func(detectors ...IPDetector) *IPPurityDetector {
+		return &IPPurityDetector{Conf: models.DefaultConf(), detectors: detectors, timeout: time.Second}
+	}
+
+	t.Run("cache hit", func(t *testing.T) {
+		d := newDetector(&stubDetector{name: "A"}, &stubDetector{name: "B"})
+		if _, cached, err := d.detectIPVerdict("1.1.1.1"); err != nil || cached {
+			t.Fatalf("first detectIPVerdict() cached = %v, %v, want false", cached, err)
+		}
+		if _, cached, err := d.detectIPVerdict("1.1.1.1"); err != nil || !cached {
+			t.Errorf("second detectIPVerdict() cached = %v, %v, want true", cached, err)
+		}
+		if ttl := cacheTTL("1.1.1.1"); ttl < 23*time.Hour {
+			t.Errorf("ttl = %v, want %v", ttl, env.Conf.IPCacheTTL)
+		}
+	})
+
+	t.Run("partial", func(t *testing.T) {
+		d := newDetector(&stubDetector{name: "A"}, &stubDetector{name: "B", err: errors.New("timeout")})
+		verdict, _, err := d.detectIPVerdict("2.2.2.2")
+		if err != nil || !verdict.Partial || len(verdict.Results) != 1 {
+			t.Fatalf("detectIPVerdict() = %+v, %v, want partial verdict", verdict, err)
+		}
+		if ttl := cacheTTL("2.2.2.2"); ttl > partialVerdictTTL {
+			t.Errorf("ttl = %v, want at most %v", ttl, partialVerdictTTL)
+		}
+	})
+
+	t.Run("shared detection", func(t *testing.T) {
+		stub := &stubDetector{name: "A", start: make(chan struct{}), gate: make(chan struct{})}
+		d := newDetector(stub)
+		var wg sync.WaitGroup
+		cached := make([]bool, 2)
+		detect := func(i int) {
+			defer wg.Done()
+			_, cached[i], _ = d.detectIPVerdict("3.3.3.3")
+		}
+		wg.Add(2)
+		go detect(0)
+		<-stub.start
+		go detect(1)
+		time.Sleep(50 * time.Millisecond)
+		close(stub.gate)
+		wg.Wait()
+		if stub.calls.Load() != 1 || cached[0] || cached[1] {
+			t.Errorf("detector calls = %d, cached = %v, want 1 call and no cache hit", stub.calls.Load(), cached)
+		}
+	})
+}
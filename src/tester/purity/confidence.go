@@ -0,0 +1,79 @@
+package purity
+
+import (
+	"strings"
+
+	"github.com/ocyss/sub-store-lab/src/models"
+	"github.com/samber/lo"
+)
+
+// DetectorSummary 单个检测器的精简结果，始终随合并结果保存
+type DetectorSummary struct {
+	Detector  string
+	Country   *string
+	UsageType *UsageType
+	RiskScore *int
+	Factors   int // 判定为真的风险因子数量
+}
+
+// Confidence 检测器之间的一致程度，值越高合并结果越可信
+type Confidence struct {
+	CountryAgreement float64 // 与合并国家一致的检测器权重占比，没有检测器返回国家时为1
+	UsageShare       float64 // 合并使用类型的检测器权重占比，没有检测器返回使用类型时为1
+	ScoreSpread      int     // 各检测器风险评分的极差
+}
+
+func summarize(r *proxiePurity) DetectorSummary {
+	return DetectorSummary{
+		Detector:  lo.FromPtr(r.DetectName),
+		Country:   r.Country,
+		UsageType: r.UsageType,
+		RiskScore: r.RiskScore,
+		Factors:   r.RiskFactors.TrueCount(),
+	}
+}
+
+// computeConfidence 根据参与合并的检测器计算一致程度
+func computeConfidence(profile *models.ScoringProfile, results []*proxiePurity, merged *PurityResult) Confidence {
+	confidence := Confidence{CountryAgreement: 1, UsageShare: 1}
+
+	countryTotal, countryAgree := 0.0, 0.0
+	usageTotal, usageAgree := 0.0, 0.0
+	for _, r := range results {
+		weight := profile.DetectorWeight(lo.FromPtr(r.DetectName))
+		if r.Country != nil && *r.Country != "" {
+			countryTotal += weight
+			if merged.Country != nil && strings.EqualFold(*r.Country, *merged.Country) {
+				countryAgree += weight
+			}
+		}
+		if r.UsageType != nil {
+			usageTotal += weight
+			if merged.UsageType != nil && *r.UsageType == *merged.UsageType {
+				usageAgree += weight
+			}
+		}
+	}
+	if countryTotal > 0 {
+		confidence.CountryAgreement = countryAgree / countryTotal
+	}
+	if usageTotal > 0 {
+		confidence.UsageShare = usageAgree / usageTotal
+	}
+
+	if len(merged.Contributions) > 0 {
+		scores := lo.Map(merged.Contributions, func(c Contribution, _ int) int { return c.Score })
+		confidence.ScoreSpread = lo.Max(scores) - lo.Min(scores)
+	}
+	return confidence
+}
+
+// Uncertain 检测器之间分歧是否超过评分配置中的阈值
+func (c *Confidence) Uncertain(profile *models.ScoringProfile) bool {
+	if c == nil {
+		return false
+	}
+	return c.CountryAgreement < profile.MinAgreement ||
+		c.UsageShare < profile.MinAgreement ||
+		(profile.MaxSpread > 0 && c.ScoreSpread > profile.MaxSpread)
+}
@@ -7,59 +7,19 @@ import (
 	"log/slog"
 	"net"
 	"net/http"
-	"strings"
-	"sync"
 	"time"
 
 	"github.com/metacubex/mihomo/common/convert"
 	"github.com/ocyss/sub-store-lab/src/env"
 	"github.com/ocyss/sub-store-lab/src/models"
+	"github.com/samber/lo"
 	"github.com/sourcegraph/conc/pool"
 	"resty.dev/v3"
 )
 
-type ApiKey struct {
-	key   string
-	keys  []string
-	index int
-	mu    sync.Mutex
-}
-
-// NewApiKey 传入一个以 "," 分割的字符串创建 ApiKey 实例
-func NewApiKey(keyStr string) *ApiKey {
-	keys := strings.Split(keyStr, ",")
-	if len(keys) == 1 {
-		return &ApiKey{
-			key: keys[0],
-		}
-	}
-	for i := range keys {
-		keys[i] = strings.TrimSpace(keys[i])
-	}
-	return &ApiKey{
-		keys: keys,
-	}
-}
-
-// Get 返回当前 key，并移动到下一个（循环使用）
-func (a *ApiKey) Get() string {
-	if a.key != "" {
-		return a.key
-	}
-	a.mu.Lock()
-	defer a.mu.Unlock()
-
-	if len(a.keys) == 0 {
-		return ""
-	}
-
-	key := a.keys[a.index]
-	a.index = (a.index + 1) % len(a.keys)
-	return key
-}
-
 type IPPurityDetector struct {
 	Conf      *models.Conf
+	Scope     string // 录制/回放时区分不同节点的出口IP查询
 	detectors []IPDetector
 	timeout   time.Duration
 }
@@ -72,24 +32,47 @@ func NewIPPurityDetector(conf *models.Conf, timeout time.Duration) *IPPurityDete
 	detectors := []IPDetector{
 		&IPInfoDetector{},
 		&IPApiDetector{},
+		NewPTRDetector(),
+	}
+
+	if MMDBAvailable() {
+		detectors = append(detectors, &MMDBDetector{})
 	}
 
 	if env.Conf.IpQualityAPIKey != "" {
-		detectors = append(detectors, NewIPQualityDetector(NewApiKey(env.Conf.IpQualityAPIKey)))
+		detectors = append(detectors, NewIPQualityDetector(NewApiKey("IPQuality", env.Conf.IpQualityAPIKey)))
 	}
 
 	if env.Conf.AbuseIPDBAPIKey != "" {
-		detectors = append(detectors, NewAbuseIPDBDetector(NewApiKey(env.Conf.AbuseIPDBAPIKey)))
+		detectors = append(detectors, NewAbuseIPDBDetector(NewApiKey("AbuseIPDB", env.Conf.AbuseIPDBAPIKey)))
 	}
 
 	if env.Conf.IpregistryAPIKey != "" {
-		detectors = append(detectors, NewIPRegistryDetector(NewApiKey(env.Conf.IpregistryAPIKey)))
+		detectors = append(detectors, NewIPRegistryDetector(NewApiKey("IPRegistry", env.Conf.IpregistryAPIKey)))
 	}
 
 	if env.Conf.IpDataAPIKey != "" {
-		detectors = append(detectors, NewIPDataDetector(NewApiKey(env.Conf.IpDataAPIKey)))
+		detectors = append(detectors, NewIPDataDetector(NewApiKey("IPData", env.Conf.IpDataAPIKey)))
+	}
+
+	if env.Conf.ProxyCheckAPIKey != "" {
+		detectors = append(detectors, NewProxyCheckDetector(NewApiKey("ProxyCheck", env.Conf.ProxyCheckAPIKey)))
 	}
 
+	if env.Conf.ScamalyticsAPIKey != "" {
+		detectors = append(detectors, NewScamalyticsDetector(NewApiKey("Scamalytics", env.Conf.ScamalyticsAPIKey), env.Conf.ScamalyticsHost))
+	}
+
+	if env.Conf.IpApiIsAPIKey != "" {
+		detectors = append(detectors, NewIPApiIsDetector(NewApiKey("IPApiIs", env.Conf.IpApiIsAPIKey)))
+	}
+
+	if env.Conf.IP2LocationAPIKey != "" {
+		detectors = append(detectors, NewIP2LocationDetector(NewApiKey("IP2Location", env.Conf.IP2LocationAPIKey)))
+	}
+
+	detectors = append(detectors, genericDetectors()...)
+
 	return &IPPurityDetector{
 		Conf:      conf,
 		detectors: detectors,
@@ -108,23 +91,50 @@ func (d *IPPurityDetector) DetectIP(transport http.RoundTripper) (*PurityResult,
 		return nil, fmt.Errorf("获取代理IP失败: %w", err)
 	}
 
+	verdict, cached, err := d.detectIPVerdict(ip)
+	if err != nil {
+		return nil, err
+	}
+	mergedResult := mergeVerdict(d.Conf, verdict)
+	mergedResult.Cached = cached
+
+	if mergedResult.RiskScore != nil {
+		purity := 100 - *mergedResult.RiskScore
+		if purity < 0 {
+			purity = 0
+		} else if purity > 100 {
+			purity = 100
+		}
+	}
+
+	return mergedResult, nil
+}
+
+// runDetectors 调用全部检测器检测IP，部分失败时仅保留成功的结果并标记为部分结果
+func (d *IPPurityDetector) runDetectors(ip string) (*IPVerdict, error) {
 	client := resty.New().
 		SetTimeout(d.timeout).
 		SetHeader("User-Agent", convert.RandUserAgent())
 	defer client.Close()
+	if store := detectorReplay(); store != nil {
+		client.SetTransport(store.Transport(client.Transport(), ""))
+	}
 
-	p := pool.NewWithResults[*proxiePurity]().WithMaxGoroutines(2).WithErrors()
-	for _, detector := range d.detectors {
-		detector := detector // 避免闭包问题
-		p.Go(func() (*proxiePurity, error) {
+	// 按检测器顺序保存结果，避免完成先后不同导致合并结果不稳定
+	slots := make([]*proxiePurity, len(d.detectors))
+	p := pool.New().WithMaxGoroutines(2).WithErrors()
+	for i, detector := range d.detectors {
+		p.Go(func() error {
 			result, err := detector.Detect(client, ip)
 			if err != nil {
-				return nil, fmt.Errorf("[%s]失败: %w", detector.Name(), err)
+				return fmt.Errorf("[%s]失败: %w", detector.Name(), err)
 			}
-			return result, nil
+			slots[i] = result
+			return nil
 		})
 	}
-	results, errs := p.Wait()
+	errs := p.Wait()
+	results := lo.Compact(slots)
 
 	if len(results) == 0 {
 		return nil, fmt.Errorf("IP风控值测试全部失败, %w", errs)
@@ -132,18 +142,12 @@ func (d *IPPurityDetector) DetectIP(transport http.RoundTripper) (*PurityResult,
 		slog.Warn("IP风控值测试部分失败", "err", errs)
 	}
 
-	mergedResult := MergeIPInfo(d.Conf, results)
-
-	if mergedResult.RiskScore != nil {
-		purity := 100 - *mergedResult.RiskScore
-		if purity < 0 {
-			purity = 0
-		} else if purity > 100 {
-			purity = 100
-		}
-	}
-
-	return mergedResult, nil
+	return &IPVerdict{
+		IP:         ip,
+		Results:    results,
+		DetectedAt: time.Now(),
+		Partial:    errs != nil,
+	}, nil
 }
 
 func (d *IPPurityDetector) getProxyIP(transport http.RoundTripper) (string, error) {
@@ -158,6 +162,10 @@ func (d *IPPurityDetector) getProxyIP(transport http.RoundTripper) (string, erro
 		"https://ipapi.co/ip/",
 	}
 
+	if store := detectorReplay(); store != nil {
+		transport = store.Transport(transport, d.Scope)
+	}
+
 	client := resty.New().
 		SetTimeout(3*time.Second).
 		SetTransport(transport).
@@ -0,0 +1,257 @@
+package purity
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/samber/lo"
+	"resty.dev/v3"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// replayClient 返回固定响应的客户端，不发起网络请求
+func replayClient(t *testing.T, status int, body string, check func(req *http.Request)) *resty.Client {
+	t.Helper()
+	return resty.New().SetTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if check != nil {
+			check(req)
+		}
+		return &http.Response{
+			StatusCode: status,
+			Status:     http.StatusText(status),
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	}))
+}
+
+const testIP = "203.0.113.88"
+
+func TestDetectors_Replay(t *testing.T) {
+	tests := []struct {
+		name     string
+		detector IPDetector
+		status   int
+		body     string
+		host     string
+		query    map[string]string
+		want     proxiePurity
+		wantErr  bool
+	}{
+		{
+			name:     "proxycheck",
+			detector: NewProxyCheckDetector(NewApiKey("test-ProxyCheck", "pc-key")),
+			status:   200,
+			body: `{"status": "ok", "203.0.113.88": {"asn": "AS9269", "provider": "Hong Kong Broadband Network Ltd.",
+				"isocode": "HK", "region": "Central and Western", "city": "Hong Kong", "proxy": "no", "type": "Residential", "risk": 0}}`,
+			host:  "proxycheck.io",
+			query: map[string]string{"key": "pc-key", "risk": "1"},
+			want: proxiePurity{
+				Country:     lo.ToPtr("HK"),
+				RiskScore:   lo.ToPtr(0),
+				ASN:         lo.ToPtr(9269),
+				Org:         lo.ToPtr("Hong Kong Broadband Network Ltd."),
+				UsageType:   lo.ToPtr(UsageTypeResidential),
+				RiskFactors: RiskFactors{IsProxy: lo.ToPtr(false), IsVPN: lo.ToPtr(false), IsServer: lo.ToPtr(false)},
+			},
+		},
+		{
+			name:     "proxycheck vpn",
+			detector: NewProxyCheckDetector(NewApiKey("test-ProxyCheck", "pc-key")),
+			status:   200,
+			body:     `{"status": "ok", "203.0.113.88": {"asn": "AS133750", "isocode": "HK", "proxy": "yes", "type": "VPN", "risk": 66}}`,
+			want: proxiePurity{
+				Country:     lo.ToPtr("HK"),
+				RiskScore:   lo.ToPtr(66),
+				ASN:         lo.ToPtr(133750),
+				UsageType:   lo.ToPtr(UsageTypeDatacenter),
+				RiskFactors: RiskFactors{IsProxy: lo.ToPtr(false), IsVPN: lo.ToPtr(true)},
+			},
+		},
+		{
+			name:     "proxycheck denied",
+			detector: NewProxyCheckDetector(NewApiKey("test-ProxyCheck-denied", "pc-key")),
+			status:   200,
+			body:     `{"status": "denied", "message": "1,000 free queries exhausted."}`,
+			wantErr:  true,
+		},
+		{
+			name:     "scamalytics",
+			detector: NewScamalyticsDetector(NewApiKey("test-Scamalytics", "user:sc-key"), "api11.scamalytics.com"),
+			status:   200,
+			body: `{"scamalytics": {"status": "ok", "ip": "203.0.113.88", "scamalytics_score": 57, "scamalytics_risk": "medium",
+				"scamalytics_isp": "HK Example Tech Co.", "scamalytics_proxy": {"is_datacenter": true, "is_vpn": false}},
+				"external_datasources": {"dbip": {"ip_country_code": "HK", "ip_city": "Hong Kong", "asn": "133750", "connection_type": "corporate"},
+				"x4bnet": {"is_tor": false, "is_vpn": false, "is_datacenter": true}, "firehol": {"is_proxy": false}}}`,
+			host:  "api11.scamalytics.com",
+			query: map[string]string{"key": "sc-key", "ip": testIP},
+			want: proxiePurity{
+				Country:     lo.ToPtr("HK"),
+				RiskScore:   lo.ToPtr(57),
+				ASN:         lo.ToPtr(133750),
+				Org:         lo.ToPtr("HK Example Tech Co."),
+				UsageType:   lo.ToPtr(UsageTypeDatacenter),
+				RiskFactors: RiskFactors{IsServer: lo.ToPtr(true), IsVPN: lo.ToPtr(false), IsTor: lo.ToPtr(false)},
+			},
+		},
+		{
+			name:     "scamalytics error",
+			detector: NewScamalyticsDetector(NewApiKey("test-Scamalytics", "user:sc-key"), "api11.scamalytics.com"),
+			status:   200,
+			body:     `{"scamalytics": {"status": "error", "error": "invalid key"}}`,
+			wantErr:  true,
+		},
+		{
+			name:     "ipapi.is",
+			detector: NewIPApiIsDetector(NewApiKey("test-IPApiIs", "is-key")),
+			status:   200,
+			body: `{"ip": "203.0.113.88", "is_datacenter": true, "is_vpn": true, "is_proxy": false, "is_tor": false, "is_abuser": false,
+				"company": {"name": "HK Example Tech Co.", "abuser_score": "0.0039 (Low)", "type": "hosting"},
+				"asn": {"asn": 133750, "abuser_score": "0.0142 (Elevated)", "org": "HK Example Tech Co.", "type": "hosting"},
+				"location": {"country_code": "HK", "state": "Hong Kong", "city": "Hong Kong"}}`,
+			host:  "api.ipapi.is",
+			query: map[string]string{"q": testIP, "key": "is-key"},
+			want: proxiePurity{
+				Country:     lo.ToPtr("HK"),
+				RiskScore:   lo.ToPtr(20),
+				ASN:         lo.ToPtr(133750),
+				Org:         lo.ToPtr("HK Example Tech Co."),
+				UsageType:   lo.ToPtr(UsageTypeDatacenter),
+				RiskFactors: RiskFactors{IsServer: lo.ToPtr(true), IsVPN: lo.ToPtr(true), IsProxy: lo.ToPtr(false)},
+			},
+		},
+		{
+			name:     "ipapi.is isp",
+			detector: NewIPApiIsDetector(NewApiKey("test-IPApiIs", "is-key")),
+			status:   200,
+			body: `{"ip": "203.0.113.88", "company": {"name": "HKBN", "type": "isp"},
+				"asn": {"asn": 9269, "abuser_score": "0.0001 (Very Low)"}, "location": {"country_code": "HK"}}`,
+			want: proxiePurity{
+				Country:   lo.ToPtr("HK"),
+				RiskScore: lo.ToPtr(5),
+				ASN:       lo.ToPtr(9269),
+				UsageType: lo.ToPtr(UsageTypeResidential),
+			},
+		},
+		{
+			name:     "ip2location",
+			detector: NewIP2LocationDetector(NewApiKey("test-IP2Location", "i2l-key")),
+			status:   200,
+			body: `{"ip": "203.0.113.88", "country_code": "HK", "region_name": "Hong Kong", "city_name": "Hong Kong",
+				"asn": "133750", "as": "HK Example Tech Co.", "is_proxy": true, "usage_type": "DCH", "fraud_score": 66,
+				"proxy": {"proxy_type": "VPN", "is_vpn": true, "is_tor": false, "is_data_center": true, "is_spammer": false}}`,
+			host:  "api.ip2location.io",
+			query: map[string]string{"key": "i2l-key", "ip": testIP},
+			want: proxiePurity{
+				Country:     lo.ToPtr("HK"),
+				RiskScore:   lo.ToPtr(66),
+				ASN:         lo.ToPtr(133750),
+				Org:         lo.ToPtr("HK Example Tech Co."),
+				UsageType:   lo.ToPtr(UsageTypeDatacenter),
+				RiskFactors: RiskFactors{IsServer: lo.ToPtr(true), IsVPN: lo.ToPtr(true), IsAbuse: lo.ToPtr(false)},
+			},
+		},
+		{
+			name:     "ip2location free",
+			detector: NewIP2LocationDetector(NewApiKey("test-IP2Location", "i2l-key")),
+			status:   200,
+			body:     `{"ip": "203.0.113.88", "country_code": "HK", "asn": "9269", "as": "HKBN", "is_proxy": false}`,
+			want: proxiePurity{
+				Country:     lo.ToPtr("HK"),
+				ASN:         lo.ToPtr(9269),
+				RiskFactors: RiskFactors{IsProxy: lo.ToPtr(false)},
+			},
+		},
+		{
+			name:     "ip2location quota",
+			detector: NewIP2LocationDetector(NewApiKey("test-IP2Location-quota", "i2l-key")),
+			status:   401,
+			body:     `{"error": {"error_code": 10003, "error_message": "Insufficient queries."}}`,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := replayClient(t, tt.status, tt.body, func(req *http.Request) {
+				if tt.host != "" && req.URL.Host != tt.host {
+					t.Errorf("host = %s, want %s", req.URL.Host, tt.host)
+				}
+				for k, v := range tt.query {
+					if got := req.URL.Query().Get(k); got != v {
+						t.Errorf("query %s = %q, want %q", k, got, v)
+					}
+				}
+			})
+			defer client.Close()
+
+			got, err := tt.detector.Detect(client, testIP)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Detect() expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Detect() error = %v", err)
+			}
+			if got.DetectName == nil || *got.DetectName != tt.detector.Name() {
+				t.Errorf("DetectName = %v, want %s", got.DetectName, tt.detector.Name())
+			}
+			checkPtr(t, "Country", got.Country, tt.want.Country)
+			checkPtr(t, "RiskScore", got.RiskScore, tt.want.RiskScore)
+			checkPtr(t, "ASN", got.ASN, tt.want.ASN)
+			checkPtr(t, "UsageType", got.UsageType, tt.want.UsageType)
+			if tt.want.Org != nil {
+				checkPtr(t, "Org", got.Org, tt.want.Org)
+			}
+			checkFactor(t, "IsProxy", got.RiskFactors.IsProxy, tt.want.RiskFactors.IsProxy)
+			checkFactor(t, "IsVPN", got.RiskFactors.IsVPN, tt.want.RiskFactors.IsVPN)
+			checkFactor(t, "IsTor", got.RiskFactors.IsTor, tt.want.RiskFactors.IsTor)
+			checkFactor(t, "IsServer", got.RiskFactors.IsServer, tt.want.RiskFactors.IsServer)
+			checkFactor(t, "IsAbuse", got.RiskFactors.IsAbuse, tt.want.RiskFactors.IsAbuse)
+		})
+	}
+}
+
+func TestDetectors_Exhausted(t *testing.T) {
+	apiKey := NewApiKey("test-exhausted", "pc-key")
+	detector := NewProxyCheckDetector(apiKey)
+	client := replayClient(t, 200, `{"status": "denied", "message": "1,000 free queries exhausted."}`, nil)
+	defer client.Close()
+
+	if _, err := detector.Detect(client, testIP); err == nil {
+		t.Fatal("Detect() expected error")
+	}
+	if _, err := detector.Detect(client, testIP); !errors.Is(err, ErrKeyExhausted) {
+		t.Errorf("Detect() after denied error = %v, want %v", err, ErrKeyExhausted)
+	}
+}
+
+func checkPtr[T comparable](t *testing.T, field string, got, want *T) {
+	t.Helper()
+	switch {
+	case got == nil && want == nil:
+	case got == nil || want == nil:
+		t.Errorf("%s = %v, want %v", field, got, want)
+	case *got != *want:
+		t.Errorf("%s = %v, want %v", field, *got, *want)
+	}
+}
+
+// checkFactor 仅在期望值非空时比较风险因子
+func checkFactor(t *testing.T, field string, got, want *bool) {
+	t.Helper()
+	if want != nil {
+		checkPtr(t, field, got, want)
+	}
+}
@@ -0,0 +1,352 @@
+package purity
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ocyss/sub-store-lab/src/env"
+	"github.com/samber/lo"
+	"gopkg.in/yaml.v3"
+	"resty.dev/v3"
+)
+
+// 通过 yaml 声明的通用检测器, 新增接口无需重新编译, 修改后自动重新加载
+// - name: IPWhois
+//   url: "https://ipwho.is/{ip}?key={key}"
+//   headers:
+//     Accept: application/json
+//   key_env: IPWHOIS_API_KEY # 密钥所在的环境变量, 自动添加 LAB_ 前缀即 LAB_IPWHOIS_API_KEY, 多个以 "," 分割自动轮换, 为空时跳过该检测器
+//   success: success         # 可选, 该字段为假时视为失败
+//   error: message           # 可选, 失败时的错误信息
+//   fields:                  # JSON路径, "." 分割, 数组使用下标: connection.asn, data.0.ip
+//     country: country_code
+//     region: region
+//     city: city
+//     asn: connection.asn
+//     org: connection.isp
+//     usage_type: connection.type
+//     risk_score: security.score
+//     proxy: security.proxy
+//     vpn: security.vpn
+//     tor: security.tor
+//     server: security.hosting
+//     abuse: security.abuser
+//     bot: security.bot
+//   usage_map:               # 可选, 原始使用类型(不区分大小写)→Residential/Datacenter/Other
+//     isp: Residential
+//     hosting: Datacenter
+//   risk_scale: 100          # 可选, 风险评分乘数, 如接口返回0-1时设为100
+
+type GenericFields struct {
+	Country   string `yaml:"country"`
+	Region    string `yaml:"region"`
+	City      string `yaml:"city"`
+	ASN       string `yaml:"asn"`
+	Org       string `yaml:"org"`
+	UsageType string `yaml:"usage_type"`
+	RiskScore string `yaml:"risk_score"`
+	Proxy     string `yaml:"proxy"`
+	VPN       string `yaml:"vpn"`
+	Tor       string `yaml:"tor"`
+	Server    string `yaml:"server"`
+	Abuse     string `yaml:"abuse"`
+	Bot       string `yaml:"bot"`
+}
+
+type GenericDefinition struct {
+	Name      string            `yaml:"name"`
+	URL       string            `yaml:"url"`
+	Headers   map[string]string `yaml:"headers"`
+	KeyEnv    string            `yaml:"key_env"`
+	Success   string            `yaml:"success"`
+	Error     string            `yaml:"error"`
+	Fields    GenericFields     `yaml:"fields"`
+	UsageMap  map[string]string `yaml:"usage_map"`
+	RiskScale float64           `yaml:"risk_scale"`
+}
+
+func loadGenericDefinitions(path string) ([]*GenericDefinition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseGenericDefinitions(data)
+}
+
+func parseGenericDefinitions(data []byte) ([]*GenericDefinition, error) {
+	var defs []*GenericDefinition
+	if err := yaml.Unmarshal(data, &defs); err != nil {
+		return nil, err
+	}
+	names := make(map[string]struct{})
+	for i, def := range defs {
+		if def.Name == "" {
+			return nil, fmt.Errorf("detector %d: name不能为空", i+1)
+		}
+		if _, ok := names[strings.ToLower(def.Name)]; ok {
+			return nil, fmt.Errorf("detector %s: name重复", def.Name)
+		}
+		if lo.ContainsBy(builtinDetectorNames, func(name string) bool { return strings.EqualFold(name, def.Name) }) {
+			return nil, fmt.Errorf("detector %s: name与内置检测器重复", def.Name)
+		}
+		names[strings.ToLower(def.Name)] = struct{}{}
+		if !strings.Contains(def.URL, "{ip}") {
+			return nil, fmt.Errorf("detector %s: url需包含 {ip}", def.Name)
+		}
+		for raw, usage := range def.UsageMap {
+			if _, ok := ParseUsageType(usage); !ok {
+				return nil, fmt.Errorf("detector %s: usage_map[%s] 未知使用类型: %s", def.Name, raw, usage)
+			}
+		}
+	}
+	return defs, nil
+}
+
+// builtinDetectorNames 内置检测器名称，通用检测器不可重名，避免评分权重按名称配置时混淆
+var builtinDetectorNames = []string{
+	"IPInfo", "IPApi", "PTR", "MMDB", "IPQuality", "AbuseIPDB", "IPRegistry",
+	"IPData", "ProxyCheck", "Scamalytics", "IPApiIs", "IP2Location",
+}
+
+// genericKeyPrefix 通用检测器密钥的名称前缀，与内置检测器的密钥状态分开保存
+const genericKeyPrefix = "generic:"
+
+var (
+	genericOnce sync.Once
+	genericFile *localFile[[]*GenericDefinition]
+)
+
+// genericDetectors 返回声明文件中的检测器，需要密钥但环境变量为空的检测器会被跳过
+func genericDetectors() []IPDetector {
+	genericOnce.Do(func() {
+		genericFile = newLocalFile(env.Conf.GenericDetectors, loadGenericDefinitions)
+	})
+	defs, ok := genericFile.Get()
+	if !ok {
+		return nil
+	}
+	return newGenericDetectors(defs)
+}
+
+// newGenericDetectors 按声明创建检测器，密钥从添加 LAB_ 前缀的环境变量读取
+func newGenericDetectors(defs []*GenericDefinition) []IPDetector {
+	detectors := make([]IPDetector, 0, len(defs))
+	for _, def := range defs {
+		var apiKey *ApiKey
+		if def.KeyEnv != "" {
+			keyEnv := env.Prefix + def.KeyEnv
+			keyStr := os.Getenv(keyEnv)
+			if keyStr == "" {
+				slog.Debug("generic detector key is empty, skip", "detector", def.Name, "env", keyEnv)
+				continue
+			}
+			apiKey = NewApiKey(genericKeyPrefix+def.Name, keyStr)
+		}
+		detectors = append(detectors, NewGenericDetector(def, apiKey))
+	}
+	return detectors
+}
+
+type GenericDetector struct {
+	Def    *GenericDefinition
+	APIKey *ApiKey
+}
+
+func NewGenericDetector(def *GenericDefinition, apiKey *ApiKey) *GenericDetector {
+	return &GenericDetector{
+		Def:    def,
+		APIKey: apiKey,
+	}
+}
+
+func (d *GenericDetector) Name() string {
+	return d.Def.Name
+}
+
+func (d *GenericDetector) Detect(client *resty.Client, ip string) (*proxiePurity, error) {
+	if client == nil {
+		return nil, fmt.Errorf("HTTP客户端不能为空")
+	}
+
+	send := func(key string) (*resty.Response, error) {
+		replacer := strings.NewReplacer("{ip}", url.QueryEscape(ip), "{key}", url.QueryEscape(key))
+		headerReplacer := strings.NewReplacer("{ip}", ip, "{key}", key)
+		req := client.R()
+		for k, v := range d.Def.Headers {
+			req.SetHeader(k, headerReplacer.Replace(v))
+		}
+		return req.Get(replacer.Replace(d.Def.URL))
+	}
+	var (
+		resp *resty.Response
+		err  error
+	)
+	if d.APIKey != nil {
+		_, resp, err = d.APIKey.Request(send)
+	} else {
+		resp, err = send("")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("请求%s失败: %w", d.Name(), err)
+	}
+
+	if resp.StatusCode() != 200 {
+		return nil, fmt.Errorf("%s返回非200状态码: %d", d.Name(), resp.StatusCode())
+	}
+
+	var body any
+	if err := json.Unmarshal(resp.Bytes(), &body); err != nil {
+		return nil, fmt.Errorf("解析%s响应失败: %w", d.Name(), err)
+	}
+	return d.parse(body, ip)
+}
+
+func (d *GenericDetector) parse(body any, ip string) (*proxiePurity, error) {
+	def := d.Def
+	if def.Success != "" {
+		if ok, _ := toBool(jsonPath(body, def.Success)); !ok {
+			msg, _ := toString(jsonPath(body, def.Error))
+			return nil, fmt.Errorf("%s返回错误: %s", d.Name(), msg)
+		}
+	} else if msg, ok := toString(jsonPath(body, def.Error)); ok && msg != "" {
+		return nil, fmt.Errorf("%s返回错误: %s", d.Name(), msg)
+	}
+
+	str := func(path string) *string {
+		v, _ := toString(jsonPath(body, path))
+		return lo.EmptyableToPtr(v)
+	}
+	flag := func(path string) *bool {
+		v, ok := toBool(jsonPath(body, path))
+		return lo.Ternary(ok, lo.ToPtr(v), nil)
+	}
+
+	f := def.Fields
+	result := &proxiePurity{
+		IP:         lo.ToPtr(ip),
+		Country:    str(f.Country),
+		Region:     str(f.Region),
+		City:       str(f.City),
+		Org:        str(f.Org),
+		DetectName: lo.ToPtr(d.Name()),
+	}
+
+	if asnStr, ok := toString(jsonPath(body, f.ASN)); ok {
+		asn, org := parseASN(asnStr)
+		if asn == nil {
+			if n, err := strconv.Atoi(asnStr); err == nil {
+				asn = lo.ToPtr(n)
+			}
+		} else if result.Org == nil {
+			result.Org = org
+		}
+		result.ASN = asn
+	}
+
+	if score, ok := toFloat(jsonPath(body, f.RiskScore)); ok {
+		if def.RiskScale > 0 {
+			score *= def.RiskScale
+		}
+		result.RiskScore = lo.ToPtr(min(max(int(score+0.5), 0), 100))
+	}
+
+	result.RiskFactors = RiskFactors{
+		IsProxy:  flag(f.Proxy),
+		IsVPN:    flag(f.VPN),
+		IsTor:    flag(f.Tor),
+		IsServer: flag(f.Server),
+		IsAbuse:  flag(f.Abuse),
+		IsBot:    flag(f.Bot),
+	}
+
+	if raw, ok := toString(jsonPath(body, f.UsageType)); ok && raw != "" {
+		result.UsageType = lo.ToPtr(d.usageType(raw))
+	}
+
+	return result, nil
+}
+
+func (d *GenericDetector) usageType(raw string) UsageType {
+	for k, v := range d.Def.UsageMap {
+		if strings.EqualFold(k, raw) {
+			usageType, _ := ParseUsageType(v)
+			return usageType
+		}
+	}
+	if usageType, ok := ParseUsageType(raw); ok {
+		return usageType
+	}
+	return UsageTypeOther
+}
+
+// jsonPath 按 "." 分割的路径读取json值，数组使用下标，路径为空或不存在返回nil
+func jsonPath(v any, path string) any {
+	if path == "" {
+		return nil
+	}
+	for part := range strings.SplitSeq(path, ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			v = node[part]
+		case []any:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil
+			}
+			v = node[i]
+		default:
+			return nil
+		}
+	}
+	return v
+}
+
+func toString(v any) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		return "", false
+	}
+}
+
+func toFloat(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func toBool(v any) (bool, bool) {
+	switch v := v.(type) {
+	case bool:
+		return v, true
+	case float64:
+		return v != 0, true
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "true", "yes", "y", "1", "ok", "success":
+			return true, true
+		case "false", "no", "n", "0", "":
+			return false, true
+		}
+	}
+	return false, false
+}
+
+var _ IPDetector = &GenericDetector{}
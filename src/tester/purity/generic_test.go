@@ -0,0 +1,180 @@
+package purity
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/samber/lo"
+)
+
+const testGenericDefinitions = `
+- name: IPWhois
+  url: "https://ipwho.is/{ip}?key={key}"
+  headers:
+    X-Key: "{key}"
+  success: success
+  error: message
+  fields:
+    country: country_code
+    city: city
+    asn: connection.asn
+    org: connection.isp
+    usage_type: connection.type
+    risk_score: security.score
+    vpn: security.vpn
+    server: security.hosting
+  usage_map:
+    isp: Residential
+    hosting: Datacenter
+  risk_scale: 100
+`
+
+func TestGenericDetector_Detect(t *testing.T) {
+	defs, err := parseGenericDefinitions([]byte(testGenericDefinitions))
+	if err != nil {
+		t.Fatalf("parseGenericDefinitions() error = %v", err)
+	}
+	detector := NewGenericDetector(defs[0], NewApiKey("test-IPWhois", "whois-key"))
+
+	tests := []struct {
+		name    string
+		body    string
+		want    proxiePurity
+		wantErr bool
+	}{
+		{
+			name: "hosting",
+			body: `{"success": true, "country_code": "HK", "city": "Hong Kong",
+				"connection": {"asn": 133750, "isp": "HK Example Tech Co.", "type": "hosting"},
+				"security": {"score": 0.66, "vpn": "yes", "hosting": true}}`,
+			want: proxiePurity{
+				Country:     lo.ToPtr("HK"),
+				ASN:         lo.ToPtr(133750),
+				Org:         lo.ToPtr("HK Example Tech Co."),
+				RiskScore:   lo.ToPtr(66),
+				UsageType:   lo.ToPtr(UsageTypeDatacenter),
+				RiskFactors: RiskFactors{IsVPN: lo.ToPtr(true), IsServer: lo.ToPtr(true)},
+			},
+		},
+		{
+			name: "asn string and unmapped usage",
+			body: `{"success": true, "country_code": "HK", "connection": {"asn": "AS9269 HKBN", "type": "Residential"}}`,
+			want: proxiePurity{
+				Country:   lo.ToPtr("HK"),
+				ASN:       lo.ToPtr(9269),
+				Org:       lo.ToPtr("HKBN"),
+				UsageType: lo.ToPtr(UsageTypeResidential),
+			},
+		},
+		{
+			name:    "failed",
+			body:    `{"success": false, "message": "Invalid IP address"}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := replayClient(t, 200, tt.body, func(req *http.Request) {
+				if req.URL.Path != "/"+testIP || req.URL.Query().Get("key") != "whois-key" || req.Header.Get("X-Key") != "whois-key" {
+					t.Errorf("unexpected request: %s %v", req.URL, req.Header)
+				}
+			})
+			defer client.Close()
+
+			got, err := detector.Detect(client, testIP)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Detect() expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Detect() error = %v", err)
+			}
+			checkPtr(t, "Country", got.Country, tt.want.Country)
+			checkPtr(t, "ASN", got.ASN, tt.want.ASN)
+			checkPtr(t, "Org", got.Org, tt.want.Org)
+			checkPtr(t, "RiskScore", got.RiskScore, tt.want.RiskScore)
+			checkPtr(t, "UsageType", got.UsageType, tt.want.UsageType)
+			checkPtr(t, "IsVPN", got.RiskFactors.IsVPN, tt.want.RiskFactors.IsVPN)
+			checkPtr(t, "IsServer", got.RiskFactors.IsServer, tt.want.RiskFactors.IsServer)
+		})
+	}
+}
+
+func Test_jsonPath(t *testing.T) {
+	body := map[string]any{
+		"data": []any{map[string]any{"ip": "1.1.1.1"}},
+		"a":    map[string]any{"b": 1.0},
+	}
+	tests := []struct {
+		path string
+		want any
+	}{
+		{"data.0.ip", "1.1.1.1"},
+		{"a.b", 1.0},
+		{"data.1.ip", nil},
+		{"a.b.c", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := jsonPath(body, tt.path); got != tt.want {
+			t.Errorf("jsonPath(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func Test_parseGenericDefinitions_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+	}{
+		{"empty name", `[{url: "https://a.example/{ip}"}]`},
+		{"duplicate", `[{name: A, url: "https://a.example/{ip}"}, {name: a, url: "https://b.example/{ip}"}]`},
+		{"missing ip", `[{name: A, url: "https://a.example/"}]`},
+		{"builtin", `[{name: IPQuality, url: "https://a.example/{ip}"}]`},
+		{"builtin case", `[{name: ptr, url: "https://a.example/{ip}"}]`},
+		{"unknown usage", `[{name: A, url: "https://a.example/{ip}", usage_map: {isp: vpn}}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if defs, err := parseGenericDefinitions([]byte(tt.yaml)); err == nil {
+				t.Errorf("parseGenericDefinitions() = %v, want error", defs)
+			}
+		})
+	}
+}
+
+func Test_newGenericDetectors(t *testing.T) {
+	defs, err := parseGenericDefinitions([]byte(`
+- name: Keyed
+  url: "https://a.example/{ip}?key={key}"
+  key_env: TEST_GENERIC_KEY
+- name: Missing
+  url: "https://b.example/{ip}?key={key}"
+  key_env: TEST_GENERIC_MISSING
+- name: Free
+  url: "https://c.example/{ip}"
+`))
+	if err != nil {
+		t.Fatalf("parseGenericDefinitions() error = %v", err)
+	}
+	// 未添加前缀的环境变量不生效
+	t.Setenv("TEST_GENERIC_MISSING", "k")
+	t.Setenv("LAB_TEST_GENERIC_KEY", "k1,k2")
+
+	detectors := newGenericDetectors(defs)
+	names := lo.Map(detectors, func(d IPDetector, _ int) string { return d.Name() })
+	if want := []string{"Keyed", "Free"}; !slices.Equal(names, want) {
+		t.Fatalf("detectors = %v, want %v", names, want)
+	}
+	keyed := detectors[0].(*GenericDetector)
+	if keyed.APIKey == nil || keyed.APIKey.name != genericKeyPrefix+"Keyed" || len(keyed.APIKey.keys) != 2 {
+		t.Errorf("APIKey = %+v, want 2 keys named %sKeyed", keyed.APIKey, genericKeyPrefix)
+	}
+	if detectors[1].(*GenericDetector).APIKey != nil {
+		t.Error("detector without key_env should not have an APIKey")
+	}
+}
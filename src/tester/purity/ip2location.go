@@ -0,0 +1,191 @@
+package purity
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/samber/lo"
+	"resty.dev/v3"
+)
+
+// https://www.ip2location.io/ip2location-documentation
+// fraud_score 0-100, 越高越风险, 仅 Security 套餐返回; usage_type/proxy 需 Plus 及以上套餐
+const IP2LocationAPI = "https://api.ip2location.io/"
+
+type IP2LocationResponse struct {
+	IP          string `json:"ip"`
+	CountryCode string `json:"country_code"`
+	CountryName string `json:"country_name"`
+	RegionName  string `json:"region_name"`
+	CityName    string `json:"city_name"`
+	TimeZone    string `json:"time_zone"`
+	Asn         string `json:"asn"`
+	As          string `json:"as"`
+	IsProxy     bool   `json:"is_proxy"`
+	UsageType   string `json:"usage_type"`
+	FraudScore  *int   `json:"fraud_score"`
+	Proxy       *struct {
+		ProxyType                  string `json:"proxy_type"`
+		Threat                     string `json:"threat"`
+		Provider                   string `json:"provider"`
+		IsVpn                      bool   `json:"is_vpn"`
+		IsTor                      bool   `json:"is_tor"`
+		IsDataCenter               bool   `json:"is_data_center"`
+		IsPublicProxy              bool   `json:"is_public_proxy"`
+		IsWebProxy                 bool   `json:"is_web_proxy"`
+		IsWebCrawler               bool   `json:"is_web_crawler"`
+		IsResidentialProxy         bool   `json:"is_residential_proxy"`
+		IsConsumerPrivacyNetwork   bool   `json:"is_consumer_privacy_network"`
+		IsEnterprisePrivateNetwork bool   `json:"is_enterprise_private_network"`
+		IsSpammer                  bool   `json:"is_spammer"`
+		IsScanner                  bool   `json:"is_scanner"`
+		IsBotnet                   bool   `json:"is_botnet"`
+	} `json:"proxy"`
+	Error *struct {
+		ErrorCode    int    `json:"error_code"`
+		ErrorMessage string `json:"error_message"`
+	} `json:"error"`
+}
+
+// {
+//   "ip": "203.0.113.88",
+//   "country_code": "HK",
+//   "country_name": "Hong Kong",
+//   "region_name": "Hong Kong",
+//   "city_name": "Hong Kong",
+//   "latitude": 22.28552,
+//   "longitude": 114.15769,
+//   "zip_code": "-",
+//   "time_zone": "+08:00",
+//   "asn": "133750",
+//   "as": "HK Example Tech Co.",
+//   "is_proxy": true,
+//   "usage_type": "DCH",
+//   "fraud_score": 66,
+//   "proxy": {
+//     "last_seen": 3,
+//     "proxy_type": "VPN",
+//     "threat": "-",
+//     "provider": "-",
+//     "is_vpn": true,
+//     "is_tor": false,
+//     "is_data_center": true,
+//     "is_public_proxy": false,
+//     "is_web_proxy": false,
+//     "is_web_crawler": false,
+//     "is_residential_proxy": false,
+//     "is_consumer_privacy_network": false,
+//     "is_enterprise_private_network": false,
+//     "is_spammer": false,
+//     "is_scanner": false,
+//     "is_botnet": false
+//   }
+// }
+
+type IP2LocationDetector struct {
+	APIKey *ApiKey
+}
+
+func NewIP2LocationDetector(apiKey *ApiKey) *IP2LocationDetector {
+	return &IP2LocationDetector{
+		APIKey: apiKey,
+	}
+}
+
+func (d *IP2LocationDetector) Name() string {
+	return "IP2Location"
+}
+
+func (d *IP2LocationDetector) Detect(client *resty.Client, ip string) (*proxiePurity, error) {
+	if client == nil {
+		return nil, fmt.Errorf("HTTP客户端不能为空")
+	}
+
+	if d.APIKey == nil {
+		return nil, fmt.Errorf("未提供IP2Location API密钥")
+	}
+
+	var ip2LocationResp IP2LocationResponse
+	key, resp, err := d.APIKey.Request(func(key string) (*resty.Response, error) {
+		ip2LocationResp = IP2LocationResponse{}
+		return client.R().
+			SetQueryParam("key", key).
+			SetQueryParam("ip", ip).
+			SetQueryParam("format", "json").
+			SetResult(&ip2LocationResp).
+			SetError(&ip2LocationResp).
+			Get(IP2LocationAPI)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("请求IP2Location API失败: %w", err)
+	}
+
+	if ip2LocationResp.Error != nil {
+		// 10000-10002: 密钥无效/未激活, 10003: 额度用完
+		if ip2LocationResp.Error.ErrorCode == 10003 {
+			d.APIKey.Exhausted(key, ip2LocationResp.Error.ErrorMessage)
+		}
+		return nil, fmt.Errorf("IP2Location API返回错误: %d %s", ip2LocationResp.Error.ErrorCode, ip2LocationResp.Error.ErrorMessage)
+	}
+
+	if resp.StatusCode() != 200 {
+		return nil, fmt.Errorf("IP2Location API返回非200状态码: %d", resp.StatusCode())
+	}
+
+	result := &proxiePurity{
+		IP:         lo.ToPtr(ip),
+		Country:    lo.EmptyableToPtr(ip2LocationResp.CountryCode),
+		Region:     lo.EmptyableToPtr(ip2LocationResp.RegionName),
+		City:       lo.EmptyableToPtr(ip2LocationResp.CityName),
+		RiskScore:  ip2LocationResp.FraudScore,
+		Org:        lo.EmptyableToPtr(ip2LocationResp.As),
+		DetectName: lo.ToPtr(d.Name()),
+	}
+	if asn, err := strconv.Atoi(ip2LocationResp.Asn); err == nil {
+		result.ASN = lo.ToPtr(asn)
+	}
+
+	usage := strings.ToUpper(ip2LocationResp.UsageType)
+	isServer := strings.Contains(usage, "DCH") || strings.Contains(usage, "CDN")
+
+	if p := ip2LocationResp.Proxy; p != nil {
+		isServer = isServer || p.IsDataCenter
+		result.RiskFactors = RiskFactors{
+			IsProxy:  lo.ToPtr(p.IsPublicProxy || p.IsWebProxy || p.IsResidentialProxy),
+			IsVPN:    lo.ToPtr(p.IsVpn || p.IsConsumerPrivacyNetwork),
+			IsTor:    lo.ToPtr(p.IsTor),
+			IsServer: lo.ToPtr(isServer),
+			IsAbuse:  lo.ToPtr(p.IsSpammer || p.IsScanner || p.IsBotnet),
+			IsBot:    lo.ToPtr(p.IsWebCrawler),
+		}
+	} else {
+		// 免费套餐仅返回 is_proxy
+		result.RiskFactors = RiskFactors{
+			IsProxy: lo.ToPtr(ip2LocationResp.IsProxy),
+		}
+		if usage != "" {
+			result.RiskFactors.IsServer = lo.ToPtr(isServer)
+		}
+	}
+
+	// usage_type 可能为 "ISP/MOB" 等组合, 按第一个判断
+	if usage != "" {
+		var usageType UsageType
+
+		primary, _, _ := strings.Cut(usage, "/")
+		switch primary {
+		case "ISP":
+			usageType = UsageTypeResidential
+		case "DCH", "CDN", "SES", "COM":
+			usageType = UsageTypeDatacenter
+		default:
+			usageType = UsageTypeOther
+		}
+		result.UsageType = lo.ToPtr(usageType)
+	}
+
+	return result, nil
+}
+
+var _ IPDetector = &IP2LocationDetector{}
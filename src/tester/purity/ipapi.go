@@ -75,9 +75,11 @@ func (d *IPApiDetector) Detect(client *resty.Client, ip string) (*proxiePurity,
 		Country: lo.ToPtr(ipApiResp.CountryCode),
 		Region:  lo.ToPtr(ipApiResp.RegionName),
 		City:    lo.ToPtr(ipApiResp.City),
+		Org:     lo.EmptyableToPtr(ipApiResp.Isp),
 
 		DetectName: lo.ToPtr(d.Name()),
 	}
+	result.ASN, _ = parseASN(ipApiResp.As)
 
 	result.RiskFactors = RiskFactors{
 		IsProxy:  lo.ToPtr(ipApiResp.Proxy),
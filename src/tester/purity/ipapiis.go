@@ -0,0 +1,196 @@
+package purity
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/samber/lo"
+	"resty.dev/v3"
+)
+
+// https://ipapi.is/developers.html
+// 无数值风险评分, abuser_score 为 "0.0142 (Elevated)" 格式, 取括号内的等级换算
+const IPApiIsAPI = "https://api.ipapi.is/"
+
+type IPApiIsResponse struct {
+	IP           string `json:"ip"`
+	Error        string `json:"error"`
+	IsBogon      bool   `json:"is_bogon"`
+	IsMobile     bool   `json:"is_mobile"`
+	IsCrawler    bool   `json:"is_crawler"`
+	IsDatacenter bool   `json:"is_datacenter"`
+	IsTor        bool   `json:"is_tor"`
+	IsProxy      bool   `json:"is_proxy"`
+	IsVpn        bool   `json:"is_vpn"`
+	IsAbuser     bool   `json:"is_abuser"`
+	Company      struct {
+		Name        string `json:"name"`
+		AbuserScore string `json:"abuser_score"`
+		Domain      string `json:"domain"`
+		Type        string `json:"type"`
+		Network     string `json:"network"`
+	} `json:"company"`
+	Asn struct {
+		Asn         int    `json:"asn"`
+		AbuserScore string `json:"abuser_score"`
+		Route       string `json:"route"`
+		Descr       string `json:"descr"`
+		Country     string `json:"country"`
+		Org         string `json:"org"`
+		Type        string `json:"type"`
+	} `json:"asn"`
+	Location struct {
+		Country     string `json:"country"`
+		CountryCode string `json:"country_code"`
+		State       string `json:"state"`
+		City        string `json:"city"`
+		Timezone    string `json:"timezone"`
+	} `json:"location"`
+}
+
+// {
+//   "ip": "203.0.113.88",
+//   "rir": "APNIC",
+//   "is_bogon": false,
+//   "is_mobile": false,
+//   "is_crawler": false,
+//   "is_datacenter": true,
+//   "is_tor": false,
+//   "is_proxy": false,
+//   "is_vpn": true,
+//   "is_abuser": false,
+//   "company": {
+//     "name": "HK Example Tech Co.",
+//     "abuser_score": "0.0039 (Low)",
+//     "domain": "example.hk",
+//     "type": "hosting",
+//     "network": "203.0.113.0 - 203.0.113.255"
+//   },
+//   "asn": {
+//     "asn": 133750,
+//     "abuser_score": "0.0142 (Elevated)",
+//     "route": "203.0.113.0/24",
+//     "descr": "EXAMPLE-HK, HK",
+//     "country": "hk",
+//     "org": "HK Example Tech Co.",
+//     "type": "hosting"
+//   },
+//   "location": {
+//     "country": "Hong Kong",
+//     "country_code": "HK",
+//     "state": "Hong Kong",
+//     "city": "Hong Kong",
+//     "timezone": "Asia/Hong_Kong"
+//   },
+//   "elapsed_ms": 0.82
+// }
+
+type IPApiIsDetector struct {
+	APIKey *ApiKey
+}
+
+func NewIPApiIsDetector(apiKey *ApiKey) *IPApiIsDetector {
+	return &IPApiIsDetector{
+		APIKey: apiKey,
+	}
+}
+
+func (d *IPApiIsDetector) Name() string {
+	return "IPApiIs"
+}
+
+func (d *IPApiIsDetector) Detect(client *resty.Client, ip string) (*proxiePurity, error) {
+	if client == nil {
+		return nil, fmt.Errorf("HTTP客户端不能为空")
+	}
+
+	if d.APIKey == nil {
+		return nil, fmt.Errorf("未提供ipapi.is API密钥")
+	}
+
+	var ipApiIsResp IPApiIsResponse
+	_, resp, err := d.APIKey.Request(func(key string) (*resty.Response, error) {
+		ipApiIsResp = IPApiIsResponse{}
+		return client.R().
+			SetQueryParam("q", ip).
+			SetQueryParam("key", key).
+			SetResult(&ipApiIsResp).
+			Get(IPApiIsAPI)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("请求ipapi.is API失败: %w", err)
+	}
+
+	if resp.StatusCode() != 200 {
+		return nil, fmt.Errorf("ipapi.is API返回非200状态码: %d", resp.StatusCode())
+	}
+
+	if ipApiIsResp.Error != "" {
+		return nil, fmt.Errorf("ipapi.is API返回错误: %s", ipApiIsResp.Error)
+	}
+
+	result := &proxiePurity{
+		IP:         lo.ToPtr(ip),
+		Country:    lo.EmptyableToPtr(ipApiIsResp.Location.CountryCode),
+		Region:     lo.EmptyableToPtr(ipApiIsResp.Location.State),
+		City:       lo.EmptyableToPtr(ipApiIsResp.Location.City),
+		RiskScore:  abuserScoreRisk(lo.CoalesceOrEmpty(ipApiIsResp.Company.AbuserScore, ipApiIsResp.Asn.AbuserScore)),
+		ASN:        lo.EmptyableToPtr(ipApiIsResp.Asn.Asn),
+		Org:        lo.EmptyableToPtr(lo.CoalesceOrEmpty(ipApiIsResp.Company.Name, ipApiIsResp.Asn.Org)),
+		DetectName: lo.ToPtr(d.Name()),
+	}
+
+	result.RiskFactors = RiskFactors{
+		IsProxy:  lo.ToPtr(ipApiIsResp.IsProxy),
+		IsVPN:    lo.ToPtr(ipApiIsResp.IsVpn),
+		IsTor:    lo.ToPtr(ipApiIsResp.IsTor),
+		IsServer: lo.ToPtr(ipApiIsResp.IsDatacenter),
+		IsAbuse:  lo.ToPtr(ipApiIsResp.IsAbuser),
+		IsBot:    lo.ToPtr(ipApiIsResp.IsCrawler),
+	}
+
+	var usageType UsageType
+
+	switch {
+	case ipApiIsResp.IsDatacenter:
+		usageType = UsageTypeDatacenter
+	case ipApiIsResp.IsMobile:
+		usageType = UsageTypeOther
+	default:
+		switch strings.ToLower(lo.CoalesceOrEmpty(ipApiIsResp.Company.Type, ipApiIsResp.Asn.Type)) {
+		case "isp":
+			usageType = UsageTypeResidential
+		case "hosting", "business":
+			usageType = UsageTypeDatacenter
+		default:
+			usageType = UsageTypeOther
+		}
+	}
+	result.UsageType = lo.ToPtr(usageType)
+
+	return result, nil
+}
+
+// abuserScoreRisk 将 "0.0142 (Elevated)" 中的等级换算为 0-100 风险评分
+func abuserScoreRisk(score string) *int {
+	_, level, ok := strings.Cut(score, "(")
+	if !ok {
+		return nil
+	}
+	switch strings.ToLower(strings.TrimSuffix(strings.TrimSpace(level), ")")) {
+	case "very low":
+		return lo.ToPtr(5)
+	case "low":
+		return lo.ToPtr(20)
+	case "elevated":
+		return lo.ToPtr(50)
+	case "high":
+		return lo.ToPtr(75)
+	case "very high":
+		return lo.ToPtr(95)
+	default:
+		return nil
+	}
+}
+
+var _ IPDetector = &IPApiIsDetector{}
@@ -156,13 +156,15 @@ func (d *IPDataDetector) Detect(client *resty.Client, ip string) (*proxiePurity,
 		return nil, fmt.Errorf("未提供IPData API密钥")
 	}
 
-	url := fmt.Sprintf(IPDataAPI, ip)
-
 	var ipDataResp IPDataResponse
-	resp, err := client.R().
-		SetQueryParam("api-key", d.APIKey.Get()).
-		SetResult(&ipDataResp).
-		Get(url)
+	_, resp, err := d.APIKey.Request(func(key string) (*resty.Response, error) {
+		ipDataResp = IPDataResponse{}
+		url := fmt.Sprintf(IPDataAPI, ip)
+		return client.R().
+			SetQueryParam("api-key", key).
+			SetResult(&ipDataResp).
+			Get(url)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("请求IPData API失败: %w", err)
 	}
@@ -176,8 +178,10 @@ func (d *IPDataDetector) Detect(client *resty.Client, ip string) (*proxiePurity,
 		Country:    lo.ToPtr(ipDataResp.CountryCode),
 		Region:     lo.ToPtr(ipDataResp.Region),
 		City:       lo.ToPtr(ipDataResp.City),
+		Org:        lo.EmptyableToPtr(ipDataResp.Asn.Name),
 		DetectName: lo.ToPtr(d.Name()),
 	}
+	result.ASN, _ = parseASN(ipDataResp.Asn.Asn)
 
 	// 设置风险因子
 	isTor := ipDataResp.Threat.IsTor
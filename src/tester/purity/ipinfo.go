@@ -13,6 +13,7 @@ const IPInfoAPI = "https://ipinfo.io/%s/json"
 
 type IPInfoResponse struct {
 	IP       string `json:"ip"`
+	Hostname string `json:"hostname"`
 	City     string `json:"city"`
 	Region   string `json:"region"`
 	Country  string `json:"country"`
@@ -66,8 +67,10 @@ func (d *IPInfoDetector) Detect(client *resty.Client, ip string) (*proxiePurity,
 		Country:    lo.ToPtr(ipInfoResp.Country),
 		Region:     lo.ToPtr(ipInfoResp.Region),
 		City:       lo.ToPtr(ipInfoResp.City),
+		Hostname:   lo.EmptyableToPtr(ipInfoResp.Hostname),
 		DetectName: lo.ToPtr(d.Name()),
 	}
+	result.ASN, result.Org = parseASN(ipInfoResp.Org)
 
 	// 不准确
 	result.RiskFactors = RiskFactors{
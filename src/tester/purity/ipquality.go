@@ -2,6 +2,7 @@ package purity
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/samber/lo"
 	"resty.dev/v3"
@@ -97,12 +98,14 @@ func (d *IPQualityDetector) Detect(client *resty.Client, ip string) (*proxiePuri
 		return nil, fmt.Errorf("未提供IPQualityScore API密钥")
 	}
 
-	url := fmt.Sprintf(IPQualityAPI, d.APIKey.Get(), ip)
-
 	var ipQualityResp IPQualityResponse
-	resp, err := client.R().
-		SetResult(&ipQualityResp).
-		Get(url)
+	key, resp, err := d.APIKey.Request(func(key string) (*resty.Response, error) {
+		ipQualityResp = IPQualityResponse{}
+		url := fmt.Sprintf(IPQualityAPI, key, ip)
+		return client.R().
+			SetResult(&ipQualityResp).
+			Get(url)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("请求IPQualityScore API失败: %w", err)
 	}
@@ -112,6 +115,10 @@ func (d *IPQualityDetector) Detect(client *resty.Client, ip string) (*proxiePuri
 	}
 
 	if !ipQualityResp.Success {
+		// 配额耗尽时仍返回200, 通过message判断
+		if msg := strings.ToLower(ipQualityResp.Message); strings.Contains(msg, "quota") || strings.Contains(msg, "exceeded") {
+			d.APIKey.Exhausted(key, ipQualityResp.Message)
+		}
 		return nil, fmt.Errorf("IPQualityScore API返回错误: %s", ipQualityResp.Message)
 	}
 
@@ -121,6 +128,9 @@ func (d *IPQualityDetector) Detect(client *resty.Client, ip string) (*proxiePuri
 		Region:     lo.ToPtr(ipQualityResp.Region),
 		City:       lo.ToPtr(ipQualityResp.City),
 		RiskScore:  lo.ToPtr(ipQualityResp.FraudScore),
+		ASN:        lo.EmptyableToPtr(ipQualityResp.ASN),
+		Org:        lo.EmptyableToPtr(ipQualityResp.ISP),
+		Hostname:   lo.EmptyableToPtr(lo.Ternary(ipQualityResp.Host != ip, ipQualityResp.Host, "")),
 		DetectName: lo.ToPtr(d.Name()),
 	}
 
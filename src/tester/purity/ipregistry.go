@@ -240,12 +240,14 @@ func (d *IPRegistryDetector) Detect(client *resty.Client, ip string) (*proxiePur
 		return nil, fmt.Errorf("未提供IPRegistry API密钥")
 	}
 
-	url := fmt.Sprintf(IPRegistryAPI, ip, d.APIKey.Get())
-
 	var ipRegistryResp IPRegistryResponse
-	resp, err := client.R().
-		SetResult(&ipRegistryResp).
-		Get(url)
+	_, resp, err := d.APIKey.Request(func(key string) (*resty.Response, error) {
+		ipRegistryResp = IPRegistryResponse{}
+		url := fmt.Sprintf(IPRegistryAPI, ip, key)
+		return client.R().
+			SetResult(&ipRegistryResp).
+			Get(url)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("请求IPRegistry API失败: %w", err)
 	}
@@ -259,6 +261,8 @@ func (d *IPRegistryDetector) Detect(client *resty.Client, ip string) (*proxiePur
 		Country:    lo.ToPtr(ipRegistryResp.Location.Country.Code),
 		Region:     lo.ToPtr(ipRegistryResp.Location.Region.Name),
 		City:       lo.ToPtr(ipRegistryResp.Location.City),
+		ASN:        lo.EmptyableToPtr(ipRegistryResp.Connection.Asn),
+		Org:        lo.EmptyableToPtr(ipRegistryResp.Connection.Organization),
 		DetectName: lo.ToPtr(d.Name()),
 	}
 
@@ -0,0 +1,247 @@
+package purity
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ocyss/sub-store-lab/src/env"
+	"github.com/oschwald/maxminddb-golang"
+	"github.com/samber/lo"
+	"resty.dev/v3"
+)
+
+// 本地 MaxMind/DB-IP 格式的 mmdb 文件, 无需网络及 API 配额
+// City: https://dev.maxmind.com/geoip/geolite2-free-geolocation-data, https://db-ip.com/db/lite.php
+// ASN→使用类型列表, 每行一个: `AS13335 Datacenter`, `4134,Residential`, # 开头为注释
+
+// localFileReloadInterval 检查文件是否变化的最小间隔
+const localFileReloadInterval = time.Minute
+
+type mmdbCityRecord struct {
+	Country struct {
+		IsoCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Subdivisions []struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"subdivisions"`
+}
+
+type mmdbASNRecord struct {
+	AutonomousSystemNumber       uint   `maxminddb:"autonomous_system_number"`
+	AutonomousSystemOrganization string `maxminddb:"autonomous_system_organization"`
+}
+
+// localFile 按修改时间热加载的本地数据文件
+type localFile[T any] struct {
+	path    string
+	load    func(path string) (T, error)
+	mu      sync.RWMutex
+	value   T
+	ok      bool
+	modTime time.Time
+	checked time.Time
+}
+
+func newLocalFile[T any](name string, load func(path string) (T, error)) *localFile[T] {
+	path := name
+	if name != "" && !filepath.IsAbs(name) {
+		path = filepath.Join(env.Conf.DataDir, name)
+	}
+	return &localFile[T]{path: path, load: load}
+}
+
+// Get 返回当前文件内容, 文件变化时重新加载, 文件不存在返回false
+func (f *localFile[T]) Get() (T, bool) {
+	f.mu.RLock()
+	if time.Since(f.checked) < localFileReloadInterval {
+		defer f.mu.RUnlock()
+		return f.value, f.ok
+	}
+	f.mu.RUnlock()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.checked = time.Now()
+	if f.path == "" {
+		return f.value, f.ok
+	}
+	stat, err := os.Stat(f.path)
+	if err != nil {
+		if f.ok {
+			slog.Warn("local file removed", "path", f.path, "error", err)
+		}
+		f.value, f.ok = *new(T), false
+		return f.value, f.ok
+	}
+	if f.ok && stat.ModTime().Equal(f.modTime) {
+		return f.value, f.ok
+	}
+	value, err := f.load(f.path)
+	if err != nil {
+		slog.Error("failed to load local file", "path", f.path, "error", err)
+		return f.value, f.ok
+	}
+	slog.Info("local file loaded", "path", f.path, "modTime", stat.ModTime())
+	f.value, f.ok, f.modTime = value, true, stat.ModTime()
+	return f.value, f.ok
+}
+
+func loadMMDB(path string) (*maxminddb.Reader, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return maxminddb.FromBytes(data)
+}
+
+func loadASNUsage(path string) (map[int]UsageType, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseASNUsage(bufio.NewScanner(f))
+}
+
+func parseASNUsage(scanner *bufio.Scanner) (map[int]UsageType, error) {
+	usage := make(map[int]UsageType)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.FieldsFunc(text, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: 格式错误: %s", line, text)
+		}
+		asn, err := strconv.Atoi(strings.TrimPrefix(strings.ToUpper(fields[0]), "AS"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: ASN错误: %s", line, fields[0])
+		}
+		usageType, ok := ParseUsageType(fields[1])
+		if !ok {
+			return nil, fmt.Errorf("line %d: 未知使用类型: %s", line, fields[1])
+		}
+		usage[asn] = usageType
+	}
+	return usage, scanner.Err()
+}
+
+// ParseUsageType 解析使用类型名称, 不区分大小写, 支持常见别名
+func ParseUsageType(str string) (UsageType, bool) {
+	switch strings.ToLower(strings.TrimSpace(str)) {
+	case "residential", "isp", "home", "家宽":
+		return UsageTypeResidential, true
+	case "datacenter", "hosting", "dc", "cdn", "business", "机房":
+		return UsageTypeDatacenter, true
+	case "other", "mobile", "edu", "gov", "其他":
+		return UsageTypeOther, true
+	default:
+		return "", false
+	}
+}
+
+var (
+	mmdbOnce    sync.Once
+	mmdbCity    *localFile[*maxminddb.Reader]
+	mmdbASN     *localFile[*maxminddb.Reader]
+	mmdbASNList *localFile[map[int]UsageType]
+)
+
+func initMMDB() {
+	mmdbOnce.Do(func() {
+		mmdbCity = newLocalFile(env.Conf.MMDBCity, loadMMDB)
+		mmdbASN = newLocalFile(env.Conf.MMDBASN, loadMMDB)
+		mmdbASNList = newLocalFile(env.Conf.ASNUsage, loadASNUsage)
+	})
+}
+
+// MMDBAvailable 是否存在可用的本地 mmdb 文件
+func MMDBAvailable() bool {
+	initMMDB()
+	_, city := mmdbCity.Get()
+	_, asn := mmdbASN.Get()
+	return city || asn
+}
+
+type MMDBDetector struct{}
+
+func (d *MMDBDetector) Name() string {
+	return "MMDB"
+}
+
+func (d *MMDBDetector) Detect(_ *resty.Client, ip string) (*proxiePurity, error) {
+	initMMDB()
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return nil, fmt.Errorf("无效IP: %s", ip)
+	}
+
+	result := &proxiePurity{
+		IP:         lo.ToPtr(ip),
+		DetectName: lo.ToPtr(d.Name()),
+	}
+
+	found := false
+	if reader, ok := mmdbCity.Get(); ok {
+		var record mmdbCityRecord
+		if err := reader.Lookup(addr, &record); err != nil {
+			return nil, fmt.Errorf("查询City数据库失败: %w", err)
+		}
+		if record.Country.IsoCode != "" {
+			found = true
+			result.Country = lo.ToPtr(record.Country.IsoCode)
+			if len(record.Subdivisions) > 0 {
+				result.Region = localName(record.Subdivisions[0].Names)
+			}
+			result.City = localName(record.City.Names)
+		}
+	}
+
+	if reader, ok := mmdbASN.Get(); ok {
+		var record mmdbASNRecord
+		if err := reader.Lookup(addr, &record); err != nil {
+			return nil, fmt.Errorf("查询ASN数据库失败: %w", err)
+		}
+		if record.AutonomousSystemNumber != 0 {
+			found = true
+			result.ASN = lo.ToPtr(int(record.AutonomousSystemNumber))
+			result.Org = lo.EmptyableToPtr(record.AutonomousSystemOrganization)
+			if usage, ok := mmdbASNList.Get(); ok {
+				if usageType, ok := usage[*result.ASN]; ok {
+					result.UsageType = lo.ToPtr(usageType)
+					result.RiskFactors.IsServer = lo.ToPtr(usageType == UsageTypeDatacenter)
+				}
+			}
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("本地数据库中未找到IP: %s", ip)
+	}
+	return result, nil
+}
+
+func localName(names map[string]string) *string {
+	if name, ok := names["en"]; ok && name != "" {
+		return lo.ToPtr(name)
+	}
+	return nil
+}
+
+var _ IPDetector = &MMDBDetector{}
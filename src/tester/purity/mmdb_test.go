@@ -0,0 +1,127 @@
+package purity
+
+import (
+	"bufio"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseUsageType(t *testing.T) {
+	tests := []struct {
+		str    string
+		want   UsageType
+		wantOk bool
+	}{
+		{"Residential", UsageTypeResidential, true},
+		{" isp ", UsageTypeResidential, true},
+		{"家宽", UsageTypeResidential, true},
+		{"HOSTING", UsageTypeDatacenter, true},
+		{"cdn", UsageTypeDatacenter, true},
+		{"机房", UsageTypeDatacenter, true},
+		{"mobile", UsageTypeOther, true},
+		{"其他", UsageTypeOther, true},
+		{"vpn", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseUsageType(tt.str)
+		if got != tt.want || ok != tt.wantOk {
+			t.Errorf("ParseUsageType(%q) = %s, %v, want %s, %v", tt.str, got, ok, tt.want, tt.wantOk)
+		}
+	}
+}
+
+func Test_parseASNUsage(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    map[int]UsageType
+		wantErr bool
+	}{
+		{
+			name: "formats",
+			text: "# 注释\n\nAS13335 Datacenter\n4134,Residential\nas9269\tisp\n",
+			want: map[int]UsageType{13335: UsageTypeDatacenter, 4134: UsageTypeResidential, 9269: UsageTypeResidential},
+		},
+		{
+			name: "later line wins",
+			text: "AS1 hosting\nAS1 mobile\n",
+			want: map[int]UsageType{1: UsageTypeOther},
+		},
+		{name: "missing type", text: "AS13335\n", wantErr: true},
+		{name: "bad asn", text: "ASX Datacenter\n", wantErr: true},
+		{name: "unknown type", text: "AS13335 vpn\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseASNUsage(bufio.NewScanner(strings.NewReader(tt.text)))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseASNUsage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !maps.Equal(got, tt.want) {
+				t.Errorf("parseASNUsage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_localFile_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "asn.txt")
+	f := newLocalFile(path, loadASNUsage)
+	// 跳过检查间隔，模拟间隔已过
+	get := func() (map[int]UsageType, bool) {
+		f.checked = time.Time{}
+		return f.Get()
+	}
+
+	if _, ok := get(); ok {
+		t.Fatal("Get() ok before file exists")
+	}
+
+	if err := os.WriteFile(path, []byte("AS1 hosting\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := get(); !ok || got[1] != UsageTypeDatacenter {
+		t.Fatalf("Get() = %v, %v, want AS1 Datacenter", got, ok)
+	}
+
+	// 检查间隔内不读取文件
+	if err := os.WriteFile(path, []byte("AS1 isp\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := f.Get(); got[1] != UsageTypeDatacenter {
+		t.Errorf("Get() within interval = %v, want cached value", got)
+	}
+
+	// 修改时间变化后重新加载
+	modTime := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := get(); !ok || got[1] != UsageTypeResidential {
+		t.Errorf("Get() after rewrite = %v, %v, want AS1 Residential", got, ok)
+	}
+
+	// 新内容无效时保留上次的内容
+	if err := os.WriteFile(path, []byte("AS1 vpn\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	modTime = modTime.Add(time.Minute)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := get(); !ok || got[1] != UsageTypeResidential {
+		t.Errorf("Get() after invalid rewrite = %v, %v, want previous value", got, ok)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := get(); ok {
+		t.Error("Get() ok after file removed")
+	}
+}
@@ -0,0 +1,179 @@
+package purity
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/samber/lo"
+	"resty.dev/v3"
+)
+
+// https://proxycheck.io/api/
+// risk 0-100, 越高越风险; type 为连接类型或代理类型
+const ProxyCheckAPI = "https://proxycheck.io/v2/%s"
+
+type ProxyCheckIPInfo struct {
+	Asn          string  `json:"asn"`
+	Provider     string  `json:"provider"`
+	Organisation string  `json:"organisation"`
+	Continent    string  `json:"continent"`
+	Country      string  `json:"country"`
+	IsoCode      string  `json:"isocode"`
+	Region       string  `json:"region"`
+	RegionCode   string  `json:"regioncode"`
+	City         string  `json:"city"`
+	Latitude     float64 `json:"latitude"`
+	Longitude    float64 `json:"longitude"`
+	Proxy        string  `json:"proxy"`
+	Type         string  `json:"type"`
+	Risk         *int    `json:"risk"`
+}
+
+// ProxyCheckResponse 以查询的IP为key, status/message 与IP信息同级
+type ProxyCheckResponse struct {
+	Status  string
+	Message string
+	Info    *ProxyCheckIPInfo
+}
+
+func (r *ProxyCheckResponse) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	for k, v := range raw {
+		switch k {
+		case "status":
+			_ = json.Unmarshal(v, &r.Status)
+		case "message":
+			_ = json.Unmarshal(v, &r.Message)
+		case "query time", "node":
+		default:
+			var info ProxyCheckIPInfo
+			if err := json.Unmarshal(v, &info); err != nil {
+				return fmt.Errorf("解析IP信息失败: %w", err)
+			}
+			r.Info = &info
+		}
+	}
+	return nil
+}
+
+// {
+//   "status": "ok",
+//   "203.0.113.88": {
+//     "asn": "AS9269",
+//     "provider": "Hong Kong Broadband Network Ltd.",
+//     "organisation": "HKBN",
+//     "continent": "Asia",
+//     "country": "Hong Kong",
+//     "isocode": "HK",
+//     "region": "Central and Western",
+//     "regioncode": "HCW",
+//     "city": "Hong Kong",
+//     "latitude": 22.2783,
+//     "longitude": 114.1747,
+//     "proxy": "no",
+//     "type": "Residential",
+//     "risk": 0
+//   }
+// }
+
+type ProxyCheckDetector struct {
+	APIKey *ApiKey
+}
+
+func NewProxyCheckDetector(apiKey *ApiKey) *ProxyCheckDetector {
+	return &ProxyCheckDetector{
+		APIKey: apiKey,
+	}
+}
+
+func (d *ProxyCheckDetector) Name() string {
+	return "ProxyCheck"
+}
+
+func (d *ProxyCheckDetector) Detect(client *resty.Client, ip string) (*proxiePurity, error) {
+	if client == nil {
+		return nil, fmt.Errorf("HTTP客户端不能为空")
+	}
+
+	if d.APIKey == nil {
+		return nil, fmt.Errorf("未提供ProxyCheck API密钥")
+	}
+
+	var proxyCheckResp ProxyCheckResponse
+	key, resp, err := d.APIKey.Request(func(key string) (*resty.Response, error) {
+		proxyCheckResp = ProxyCheckResponse{}
+		url := fmt.Sprintf(ProxyCheckAPI, ip)
+		return client.R().
+			SetQueryParams(map[string]string{
+				"key":  key,
+				"vpn":  "1",
+				"asn":  "1",
+				"risk": "1",
+			}).
+			SetResult(&proxyCheckResp).
+			Get(url)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("请求ProxyCheck API失败: %w", err)
+	}
+
+	if resp.StatusCode() != 200 {
+		return nil, fmt.Errorf("ProxyCheck API返回非200状态码: %d", resp.StatusCode())
+	}
+
+	// status: ok/warning/denied/error, 日配额耗尽时返回denied
+	switch proxyCheckResp.Status {
+	case "ok", "warning":
+	case "denied":
+		d.APIKey.Exhausted(key, proxyCheckResp.Message)
+		return nil, fmt.Errorf("ProxyCheck API拒绝请求: %s", proxyCheckResp.Message)
+	default:
+		return nil, fmt.Errorf("ProxyCheck API返回错误: %s", proxyCheckResp.Message)
+	}
+
+	info := proxyCheckResp.Info
+	if info == nil {
+		return nil, fmt.Errorf("ProxyCheck API未返回IP信息")
+	}
+
+	result := &proxiePurity{
+		IP:         lo.ToPtr(ip),
+		Country:    lo.EmptyableToPtr(info.IsoCode),
+		Region:     lo.EmptyableToPtr(info.Region),
+		City:       lo.EmptyableToPtr(info.City),
+		RiskScore:  info.Risk,
+		Org:        lo.EmptyableToPtr(info.Provider),
+		DetectName: lo.ToPtr(d.Name()),
+	}
+	result.ASN, _ = parseASN(info.Asn)
+
+	connType := strings.ToLower(info.Type)
+	isProxy := info.Proxy == "yes"
+	result.RiskFactors = RiskFactors{
+		IsProxy:  lo.ToPtr(isProxy && connType != "vpn"),
+		IsVPN:    lo.ToPtr(connType == "vpn" || connType == "openvpn"),
+		IsTor:    lo.ToPtr(connType == "tor"),
+		IsServer: lo.ToPtr(connType == "hosting"),
+		IsAbuse:  lo.ToPtr(connType == "compromised server"),
+	}
+
+	var usageType UsageType
+
+	switch connType {
+	case "residential", "wireless":
+		usageType = UsageTypeResidential
+	case "hosting", "business", "vpn", "openvpn":
+		usageType = UsageTypeDatacenter
+	default:
+		usageType = UsageTypeOther
+	}
+	result.UsageType = lo.ToPtr(usageType)
+
+	return result, nil
+}
+
+var _ IPDetector = &ProxyCheckDetector{}
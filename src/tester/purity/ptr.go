@@ -0,0 +1,135 @@
+package purity
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+	"resty.dev/v3"
+)
+
+// 反向解析出口IP的PTR记录, 按主机名特征判断使用类型, 无需API密钥
+// 仅提供使用类型, 作为独立于各接口的一票参与合并
+
+const ptrLookupTimeout = 3 * time.Second
+
+type hostnamePattern struct {
+	usageType UsageType
+	suffixes  []string       // 域名后缀
+	keywords  *regexp.Regexp // 以非字母分隔的关键词
+}
+
+// keywordPattern 匹配以非字母分隔的关键词，关键词后可跟数字，如 dsl01、pool-1-2-3-4
+func keywordPattern(words ...string) *regexp.Regexp {
+	return regexp.MustCompile(`(^|[^a-z])(` + strings.Join(words, "|") + `)[0-9]*([^a-z]|$)`)
+}
+
+// hostnamePatterns 按顺序匹配，云厂商/机房优先，避免 static.xx.clients.your-server.de 等被误判为家宽
+var hostnamePatterns = []hostnamePattern{
+	{
+		usageType: UsageTypeDatacenter,
+		suffixes: []string{
+			"amazonaws.com", "googleusercontent.com", "cloudapp.net", "cloudapp.azure.com",
+			"linodeusercontent.com", "members.linode.com", "vultrusercontent.com", "vultr.com", "choopa.net",
+			"digitalocean.com", "your-server.de", "hetzner.com", "hetzner.cloud", "ovh.net", "ovh.ca", "ovh.us",
+			"contaboserver.net", "leaseweb.net", "oraclecloud.com", "aliyun.com", "alibabacloud.com",
+			"tencentcloud.com", "bandwagonhost.com", "kamatera.com", "upcloud.host", "scaleway.com",
+			"dmit.io", "racknerd.com", "colocrossing.com", "hostwinds.com", "m247.com", "datapacket.com",
+		},
+		keywords: keywordPattern("vps", "server", "srv", "hosting", "hosted", "cloud", "dedi", "dedicated", "colo", "datacenter", "compute"),
+	},
+	{
+		usageType: UsageTypeOther,
+		suffixes: []string{
+			"tmodns.net", "myvzw.com", "mycingular.net", "mobile.att.net", "spcsdns.net",
+		},
+		keywords: keywordPattern("mobile", "mobi", "wireless", "lte", "gprs", "cellular", "umts", "3g", "4g", "5g"),
+	},
+	{
+		usageType: UsageTypeResidential,
+		suffixes: []string{
+			"comcast.net", "verizon.net", "rr.com", "cox.net", "charter.com", "spectrum.com", "sbcglobal.net",
+			"hinet.net", "ocn.ne.jp", "bbtec.net", "netvigator.com", "ctinets.com", "hkbn.net", "kbronet.com.tw",
+			"btcentralplus.com", "virginm.net", "t-ipconnect.de", "wanadoo.fr", "bbox.fr",
+		},
+		keywords: keywordPattern("dyn", "dynamic", "pool", "dsl", "adsl", "vdsl", "xdsl", "cable", "fiber", "fibre",
+			"ftth", "fttx", "broadband", "dhcp", "cpe", "ppp", "pppoe", "dialup", "dial", "home", "res", "residential",
+			"customer", "cust", "user", "client", "static"),
+	},
+}
+
+// ClassifyHostname 按主机名特征判断使用类型
+func ClassifyHostname(hostname string) (UsageType, bool) {
+	hostname = strings.TrimSuffix(strings.ToLower(hostname), ".")
+	if hostname == "" {
+		return "", false
+	}
+	for _, p := range hostnamePatterns {
+		for _, suffix := range p.suffixes {
+			if hostname == suffix || strings.HasSuffix(hostname, "."+suffix) {
+				return p.usageType, true
+			}
+		}
+	}
+	// 关键词只检查主机名部分，排除顶级域名及二级域名
+	labels := strings.Split(hostname, ".")
+	host := strings.Join(labels[:max(len(labels)-2, 1)], ".")
+	for _, p := range hostnamePatterns {
+		if p.keywords.MatchString(host) {
+			return p.usageType, true
+		}
+	}
+	return "", false
+}
+
+type PTRDetector struct {
+	lookup func(ctx context.Context, addr string) ([]string, error)
+}
+
+func NewPTRDetector() *PTRDetector {
+	lookup := net.DefaultResolver.LookupAddr
+	if store := detectorReplay(); store != nil {
+		lookup = store.LookupAddr(lookup)
+	}
+	return &PTRDetector{
+		lookup: lookup,
+	}
+}
+
+func (d *PTRDetector) Name() string {
+	return "PTR"
+}
+
+func (d *PTRDetector) Detect(_ *resty.Client, ip string) (*proxiePurity, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ptrLookupTimeout)
+	defer cancel()
+
+	names, err := d.lookup(ctx, ip)
+	if err != nil {
+		return nil, fmt.Errorf("PTR查询失败: %w", err)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("未找到PTR记录: %s", ip)
+	}
+
+	for _, name := range names {
+		usageType, ok := ClassifyHostname(name)
+		if !ok {
+			continue
+		}
+		return &proxiePurity{
+			IP:         lo.ToPtr(ip),
+			Hostname:   lo.ToPtr(strings.TrimSuffix(name, ".")),
+			UsageType:  lo.ToPtr(usageType),
+			DetectName: lo.ToPtr(d.Name()),
+			// 不提供风险因素及风险值，避免主机名特征被推算为风险值参与评分
+		}, nil
+	}
+	return nil, fmt.Errorf("无法识别PTR记录: %s", strings.Join(names, ","))
+}
+
+var _ IPDetector = &PTRDetector{}
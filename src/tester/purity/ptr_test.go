@@ -0,0 +1,62 @@
+package purity
+
+import (
+	"context"
+	"testing"
+)
+
+func TestClassifyHostname(t *testing.T) {
+	tests := []struct {
+		hostname string
+		want     UsageType
+		wantOk   bool
+	}{
+		{"ec2-54-1-2-3.ap-east-1.compute.amazonaws.com.", UsageTypeDatacenter, true},
+		{"static.88-198-1-2.clients.your-server.de", UsageTypeDatacenter, true},
+		{"vps-12345.example.net", UsageTypeDatacenter, true},
+		{"server01.example.com", UsageTypeDatacenter, true},
+		{"ip-1-2.mobile.example.com", UsageTypeOther, true},
+		{"1-2-3-4.4g.example.jp", UsageTypeOther, true},
+		{"c-73-1-2-3.hsd1.ca.comcast.net", UsageTypeResidential, true},
+		{"p1234-ipngn100.osaka.ocn.ne.jp", UsageTypeResidential, true},
+		{"1-2-3-4.dyn.example.org", UsageTypeResidential, true},
+		{"pool-71-1-2-3.nycmny.example.net", UsageTypeResidential, true},
+		{"adsl-1-2-3-4.example.com", UsageTypeResidential, true},
+		{"static-1-2-3-4.example.com", UsageTypeResidential, true},
+		{"mail.serverless.com", "", false}, // 关键词需以非字母分隔，且不检查二级域名
+		{"dynamo.example.com", "", false},
+		{"1-2-3-4.example.com", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := ClassifyHostname(tt.hostname)
+		if got != tt.want || ok != tt.wantOk {
+			t.Errorf("ClassifyHostname(%q) = %q, %v, want %q, %v", tt.hostname, got, ok, tt.want, tt.wantOk)
+		}
+	}
+}
+
+func TestPTRDetector_Detect(t *testing.T) {
+	detector := &PTRDetector{
+		lookup: func(_ context.Context, addr string) ([]string, error) {
+			if addr != testIP {
+				t.Errorf("lookup addr = %s, want %s", addr, testIP)
+			}
+			return []string{"1-2-3-4.example.com.", "1-2-3-4.dyn.example.com."}, nil
+		},
+	}
+	got, err := detector.Detect(nil, testIP)
+	if err != nil {
+		t.Fatalf("Detect() error = %v", err)
+	}
+	if got.UsageType == nil || *got.UsageType != UsageTypeResidential {
+		t.Errorf("UsageType = %v, want %s", got.UsageType, UsageTypeResidential)
+	}
+	if got.Hostname == nil || *got.Hostname != "1-2-3-4.dyn.example.com" {
+		t.Errorf("Hostname = %v, want 1-2-3-4.dyn.example.com", got.Hostname)
+	}
+	// 仅对使用类型投票，不参与风险值计算
+	if !got.RiskFactors.AllNil() || got.RiskScore != nil {
+		t.Errorf("RiskFactors = %+v, RiskScore = %v, want unset", got.RiskFactors, got.RiskScore)
+	}
+}
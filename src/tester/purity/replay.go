@@ -0,0 +1,207 @@
+package purity
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ocyss/sub-store-lab/src/env"
+)
+
+// 检测器录制/回放模式, 用于无外网的CI及测试环境, 由环境变量 LAB_DETECTOR_MODE 控制:
+// record: 正常请求并将响应写入 DATA_DIR/LAB_DETECTOR_REPLAY_DIR
+// replay: 只从录制文件读取响应, 不发起任何请求, 未录制的请求返回错误
+// 出口IP查询经过代理, 录制文件按节点区分; 检测器接口只与出口IP有关, 各节点共享
+
+const (
+	DetectorModeRecord = "record"
+	DetectorModeReplay = "replay"
+)
+
+// secretParams 录制文件中需要脱敏的查询参数，密钥变化不影响回放
+var secretParams = []string{"key", "api-key", "api_key", "apikey", "token", "access_token"}
+
+// replayEntry 录制的单个请求及响应
+type replayEntry struct {
+	Scope  string      `json:"scope,omitempty"`
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body"`
+	Names  []string    `json:"names,omitempty"` // PTR查询结果
+	Error  string      `json:"error,omitempty"`
+}
+
+// redactURL 去掉URL中的密钥参数及路径中已配置的密钥
+func redactURL(u *url.URL) string {
+	redacted := *u
+	query := redacted.Query()
+	for _, param := range secretParams {
+		if query.Has(param) {
+			query.Set(param, "REDACTED")
+		}
+	}
+	redacted.RawQuery = query.Encode()
+	s := redacted.String()
+	for _, key := range knownKeys() {
+		if len(key) < 4 {
+			continue
+		}
+		s = strings.ReplaceAll(s, url.PathEscape(key), "REDACTED")
+	}
+	return s
+}
+
+type replayStore struct {
+	mode string
+	dir  string
+}
+
+// detectorReplay 返回当前的录制/回放配置，未启用时返回nil
+func detectorReplay() *replayStore {
+	switch env.Conf.DetectorMode {
+	case DetectorModeRecord, DetectorModeReplay:
+		dir := env.Conf.DetectorReplayDir
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(env.Conf.DataDir, dir)
+		}
+		return &replayStore{mode: env.Conf.DetectorMode, dir: dir}
+	default:
+		return nil
+	}
+}
+
+func (s *replayStore) path(group string, id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return filepath.Join(s.dir, group, hex.EncodeToString(sum[:8])+".json")
+}
+
+func (s *replayStore) load(path string) (*replayEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("回放记录不存在: %s", path)
+		}
+		return nil, err
+	}
+	var entry replayEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("回放记录格式错误 %s: %w", path, err)
+	}
+	return &entry, nil
+}
+
+func (s *replayStore) save(path string, entry *replayEntry) error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Transport 包装http传输层，scope用于区分经过不同代理的相同请求
+func (s *replayStore) Transport(next http.RoundTripper, scope string) http.RoundTripper {
+	return &replayTransport{store: s, next: next, scope: scope}
+}
+
+type replayTransport struct {
+	store *replayStore
+	next  http.RoundTripper
+	scope string
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	redacted := redactURL(req.URL)
+	path := t.store.path(req.URL.Host, strings.Join([]string{t.scope, req.Method, redacted}, " "))
+
+	if t.store.mode == DetectorModeReplay {
+		entry, err := t.store.load(path)
+		if err != nil {
+			return nil, err
+		}
+		return &http.Response{
+			StatusCode:    entry.Status,
+			Status:        fmt.Sprintf("%d %s", entry.Status, http.StatusText(entry.Status)),
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        entry.Header,
+			Body:          io.NopCloser(strings.NewReader(entry.Body)),
+			ContentLength: int64(len(entry.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	header := make(http.Header)
+	for _, k := range []string{"Content-Type", "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"} {
+		if v := resp.Header.Values(k); len(v) > 0 {
+			header[k] = v
+		}
+	}
+	entry := &replayEntry{
+		Scope:  t.scope,
+		Method: req.Method,
+		URL:    redacted,
+		Status: resp.StatusCode,
+		Header: header,
+		Body:   string(body),
+	}
+	if err := t.store.save(path, entry); err != nil {
+		return nil, fmt.Errorf("保存录制文件失败: %w", err)
+	}
+	return resp, nil
+}
+
+// LookupAddr 录制/回放PTR查询
+func (s *replayStore) LookupAddr(next func(ctx context.Context, addr string) ([]string, error)) func(ctx context.Context, addr string) ([]string, error) {
+	return func(ctx context.Context, addr string) ([]string, error) {
+		path := s.path("ptr", "PTR "+addr)
+		if s.mode == DetectorModeReplay {
+			entry, err := s.load(path)
+			if err != nil {
+				return nil, err
+			}
+			if entry.Error != "" {
+				return nil, fmt.Errorf("%s", entry.Error)
+			}
+			return entry.Names, nil
+		}
+		names, err := next(ctx, addr)
+		entry := &replayEntry{Method: "PTR", URL: addr, Names: names}
+		if err != nil {
+			entry.Error = err.Error()
+		}
+		if saveErr := s.save(path, entry); saveErr != nil {
+			return nil, fmt.Errorf("保存录制文件失败: %w", saveErr)
+		}
+		return names, err
+	}
+}
@@ -0,0 +1,97 @@
+package purity
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ocyss/sub-store-lab/src/env"
+)
+
+func withDetectorMode(t *testing.T, mode string, dataDir string) *replayStore {
+	t.Helper()
+	oldMode, oldDir := env.Conf.DetectorMode, env.Conf.DataDir
+	t.Cleanup(func() {
+		env.Conf.DetectorMode, env.Conf.DataDir = oldMode, oldDir
+	})
+	env.Conf.DetectorMode, env.Conf.DataDir = mode, dataDir
+	return detectorReplay()
+}
+
+func TestReplayTransport(t *testing.T) {
+	dataDir := t.TempDir()
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ip": "` + r.URL.Query().Get("ip") + `"}`))
+	}))
+	get := func(t *testing.T, store *replayStore, scope string, key string) (string, error) {
+		client := &http.Client{Transport: store.Transport(nil, scope)}
+		resp, err := client.Get(server.URL + "/lookup?ip=" + testIP + "&key=" + key)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	store := withDetectorMode(t, DetectorModeRecord, dataDir)
+	want := `{"ip": "` + testIP + `"}`
+	if got, err := get(t, store, "node-a", "secret-key"); err != nil || got != want {
+		t.Fatalf("record = %q, %v, want %q", got, err, want)
+	}
+	files, _ := filepath.Glob(filepath.Join(dataDir, "replay", "*", "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("recorded files = %v, want 1", files)
+	}
+	data, _ := os.ReadFile(files[0])
+	if strings.Contains(string(data), "secret-key") {
+		t.Errorf("recorded file contains api key: %s", data)
+	}
+	server.Close()
+
+	store = withDetectorMode(t, DetectorModeReplay, dataDir)
+	// 密钥变化不影响回放
+	if got, err := get(t, store, "node-a", "another-key"); err != nil || got != want {
+		t.Errorf("replay = %q, %v, want %q", got, err, want)
+	}
+	if _, err := get(t, store, "node-b", "secret-key"); err == nil {
+		t.Error("replay with another scope expected error")
+	}
+	if hits != 1 {
+		t.Errorf("server hits = %d, want 1", hits)
+	}
+}
+
+func TestReplayLookupAddr(t *testing.T) {
+	dataDir := t.TempDir()
+	live := func(_ context.Context, addr string) ([]string, error) {
+		if addr == testIP {
+			return []string{"1-2-3-4.dyn.example.com."}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+
+	record := withDetectorMode(t, DetectorModeRecord, dataDir).LookupAddr(live)
+	record(context.Background(), testIP)
+	record(context.Background(), "192.0.2.1")
+
+	replay := withDetectorMode(t, DetectorModeReplay, dataDir).LookupAddr(func(context.Context, string) ([]string, error) {
+		t.Fatal("replay must not call live lookup")
+		return nil, nil
+	})
+	if names, err := replay(context.Background(), testIP); err != nil || len(names) != 1 {
+		t.Errorf("replay(%s) = %v, %v", testIP, names, err)
+	}
+	if _, err := replay(context.Background(), "192.0.2.1"); err == nil || err.Error() != "no such host" {
+		t.Errorf("replay(192.0.2.1) error = %v, want recorded error", err)
+	}
+}
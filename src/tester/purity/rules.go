@@ -0,0 +1,186 @@
+package purity
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ocyss/sub-store-lab/src/env"
+	"github.com/ocyss/sub-store-lab/src/models"
+	"github.com/samber/lo"
+	"gopkg.in/yaml.v3"
+)
+
+// 自定义分类规则, 修正检测器对已知ASN/网段的误判, 按顺序匹配, 第一条命中的规则生效
+// - name: hkbn
+//   asn: [9269]
+//   cidr: ["203.0.113.0/24"]
+//   org: "(?i)hong kong broadband"
+//   usage_type: Residential
+//   risk_score: 10
+
+type ruleConfig struct {
+	Name      string   `yaml:"name"`
+	ASN       []int    `yaml:"asn"`
+	CIDR      []string `yaml:"cidr"`
+	Org       string   `yaml:"org"`        // 组织/运营商名称正则
+	UsageType string   `yaml:"usage_type"` // 为空不修改
+	RiskScore *int     `yaml:"risk_score"` // 为空不修改
+}
+
+type classifyRule struct {
+	name      string
+	asn       map[int]struct{}
+	nets      []*net.IPNet
+	org       *regexp.Regexp
+	usageType *UsageType
+	riskScore *int
+}
+
+// RuleMatch 命中的分类规则
+type RuleMatch struct {
+	Name      string
+	Reason    string // 命中条件，如 asn:9269
+	UsageType *UsageType
+	RiskScore *int
+}
+
+func loadRules(path string) ([]*classifyRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseRules(data)
+}
+
+func parseRules(data []byte) ([]*classifyRule, error) {
+	var configs []ruleConfig
+	if err := yaml.Unmarshal(data, &configs); err != nil {
+		return nil, err
+	}
+	rules := make([]*classifyRule, 0, len(configs))
+	for i, c := range configs {
+		rule := &classifyRule{
+			name:      lo.CoalesceOrEmpty(c.Name, fmt.Sprintf("rule-%d", i+1)),
+			asn:       make(map[int]struct{}),
+			riskScore: c.RiskScore,
+		}
+		for _, asn := range c.ASN {
+			rule.asn[asn] = struct{}{}
+		}
+		for _, cidr := range c.CIDR {
+			_, ipNet, err := net.ParseCIDR(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("rule %s: CIDR错误: %w", rule.name, err)
+			}
+			rule.nets = append(rule.nets, ipNet)
+		}
+		if c.Org != "" {
+			org, err := regexp.Compile(c.Org)
+			if err != nil {
+				return nil, fmt.Errorf("rule %s: org正则错误: %w", rule.name, err)
+			}
+			rule.org = org
+		}
+		if c.UsageType != "" {
+			usageType, ok := ParseUsageType(c.UsageType)
+			if !ok {
+				return nil, fmt.Errorf("rule %s: 未知使用类型: %s", rule.name, c.UsageType)
+			}
+			rule.usageType = lo.ToPtr(usageType)
+		}
+		if rule.riskScore != nil && (*rule.riskScore < 0 || *rule.riskScore > 100) {
+			return nil, fmt.Errorf("rule %s: 风险评分需在0-100之间: %d", rule.name, *rule.riskScore)
+		}
+		if len(rule.asn) == 0 && len(rule.nets) == 0 && rule.org == nil {
+			return nil, fmt.Errorf("rule %s: 至少需要asn/cidr/org中的一项", rule.name)
+		}
+		if rule.usageType == nil && rule.riskScore == nil {
+			return nil, fmt.Errorf("rule %s: 至少需要usage_type/risk_score中的一项", rule.name)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// match 依次检查IP、ASN及组织名称，返回命中条件
+func (r *classifyRule) match(ip net.IP, asns []int, orgs []string) (string, bool) {
+	for _, ipNet := range r.nets {
+		if ip != nil && ipNet.Contains(ip) {
+			return "cidr:" + ipNet.String(), true
+		}
+	}
+	for _, asn := range asns {
+		if _, ok := r.asn[asn]; ok {
+			return "asn:" + strconv.Itoa(asn), true
+		}
+	}
+	if r.org != nil {
+		for _, org := range orgs {
+			if r.org.MatchString(org) {
+				return "org:" + org, true
+			}
+		}
+	}
+	return "", false
+}
+
+// matchRules 返回第一条命中的规则，ASN及组织名称取自全部检测器的结果
+func matchRules(rules []*classifyRule, ip string, results []*proxiePurity) *RuleMatch {
+	addr := net.ParseIP(ip)
+	asns := lo.Uniq(lo.FilterMap(results, func(r *proxiePurity, _ int) (int, bool) {
+		return lo.FromPtr(r.ASN), r.ASN != nil
+	}))
+	orgs := lo.Uniq(lo.FilterMap(results, func(r *proxiePurity, _ int) (string, bool) {
+		return strings.TrimSpace(lo.FromPtr(r.Org)), r.Org != nil
+	}))
+	for _, rule := range rules {
+		if reason, ok := rule.match(addr, asns, orgs); ok {
+			return &RuleMatch{
+				Name:      rule.name,
+				Reason:    reason,
+				UsageType: rule.usageType,
+				RiskScore: rule.riskScore,
+			}
+		}
+	}
+	return nil
+}
+
+var (
+	rulesOnce sync.Once
+	rulesFile *localFile[[]*classifyRule]
+)
+
+// applyRules 按规则文件修正合并结果，规则文件不存在时不处理
+func applyRules(conf *models.Conf, merged *PurityResult, ip string, results []*proxiePurity) {
+	rulesOnce.Do(func() {
+		rulesFile = newLocalFile(env.Conf.PurityRules, loadRules)
+	})
+	rules, ok := rulesFile.Get()
+	if !ok || len(rules) == 0 {
+		return
+	}
+	match := matchRules(rules, ip, results)
+	if match == nil {
+		return
+	}
+	if match.UsageType != nil {
+		merged.UsageType = match.UsageType
+		merged.RiskFactors.IsServer = lo.ToPtr(*match.UsageType == UsageTypeDatacenter)
+	}
+	if match.RiskScore != nil {
+		merged.RiskScore = match.RiskScore
+		merged.ScoreFloor = ""
+	} else if match.UsageType != nil && merged.RiskScore != nil {
+		// 仅修改使用类型时，按新类型重新计算最低评分，原类型的修正不再适用
+		score := lo.SumBy(merged.Contributions, func(c Contribution) float64 { return c.Points })
+		applyFloors(&conf.Scoring, merged, int(math.Round(score)))
+	}
+	merged.Rule = match
+}
@@ -0,0 +1,157 @@
+package purity
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ocyss/sub-store-lab/src/models"
+	"github.com/samber/lo"
+)
+
+const testRules = `
+- name: hkbn
+  asn: [9269]
+  usage_type: Residential
+- name: office
+  cidr: ["198.51.100.0/24"]
+  risk_score: 5
+- org: "(?i)example hosting"
+  usage_type: hosting
+  risk_score: 90
+`
+
+func Test_matchRules(t *testing.T) {
+	rules, err := parseRules([]byte(testRules))
+	if err != nil {
+		t.Fatalf("parseRules() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		ip         string
+		results    []*proxiePurity
+		wantName   string
+		wantReason string
+	}{
+		{
+			name:       "asn from any detector",
+			ip:         "203.0.113.1",
+			results:    []*proxiePurity{{ASN: lo.ToPtr(4134)}, {ASN: lo.ToPtr(9269)}},
+			wantName:   "hkbn",
+			wantReason: "asn:9269",
+		},
+		{
+			name:       "cidr",
+			ip:         "198.51.100.7",
+			results:    []*proxiePurity{{ASN: lo.ToPtr(4134)}},
+			wantName:   "office",
+			wantReason: "cidr:198.51.100.0/24",
+		},
+		{
+			name:       "org regex",
+			ip:         "203.0.113.1",
+			results:    []*proxiePurity{{Org: lo.ToPtr("Example Hosting LLC")}},
+			wantName:   "rule-3",
+			wantReason: "org:Example Hosting LLC",
+		},
+		{
+			name:    "no match",
+			ip:      "203.0.113.1",
+			results: []*proxiePurity{{ASN: lo.ToPtr(4134), Org: lo.ToPtr("CHINANET")}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := matchRules(rules, tt.ip, tt.results)
+			if tt.wantName == "" {
+				if got != nil {
+					t.Errorf("matchRules() = %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("matchRules() = nil, want %s", tt.wantName)
+			}
+			if got.Name != tt.wantName || got.Reason != tt.wantReason {
+				t.Errorf("matchRules() = %s(%s), want %s(%s)", got.Name, got.Reason, tt.wantName, tt.wantReason)
+			}
+		})
+	}
+}
+
+func Test_applyRules_UsageTypeFloor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte(testRules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rulesOnce.Do(func() {})
+	old := rulesFile
+	rulesFile = newLocalFile(path, loadRules)
+	t.Cleanup(func() { rulesFile = old })
+
+	conf := models.DefaultConf()
+	tests := []struct {
+		name      string
+		ip        string
+		results   []*proxiePurity
+		wantType  UsageType
+		wantScore int
+		wantFloor string
+	}{
+		{
+			// 检测器判断为机房，触发机房最低评分，规则改为家宽后不再适用
+			name: "datacenter to residential",
+			ip:   "203.0.113.1",
+			results: []*proxiePurity{
+				{DetectName: lo.ToPtr("A"), ASN: lo.ToPtr(9269), UsageType: lo.ToPtr(UsageTypeDatacenter), RiskScore: lo.ToPtr(10)},
+				{DetectName: lo.ToPtr("B"), UsageType: lo.ToPtr(UsageTypeDatacenter), RiskScore: lo.ToPtr(20)},
+			},
+			wantType:  UsageTypeResidential,
+			wantScore: 15,
+			wantFloor: "",
+		},
+		{
+			name: "rule risk score wins",
+			ip:   "198.51.100.7",
+			results: []*proxiePurity{
+				{DetectName: lo.ToPtr("A"), UsageType: lo.ToPtr(UsageTypeDatacenter), RiskScore: lo.ToPtr(10)},
+			},
+			wantType:  UsageTypeDatacenter,
+			wantScore: 5,
+			wantFloor: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := MergeIPInfo(conf, tt.results)
+			if merged.ScoreFloor != "datacenter" {
+				t.Fatalf("ScoreFloor before rules = %q, want datacenter", merged.ScoreFloor)
+			}
+			applyRules(conf, merged, tt.ip, tt.results)
+			if merged.Rule == nil {
+				t.Fatal("applyRules() did not match")
+			}
+			if *merged.UsageType != tt.wantType || lo.FromPtr(merged.RiskScore) != tt.wantScore || merged.ScoreFloor != tt.wantFloor {
+				t.Errorf("applyRules() = %s/%d/%q, want %s/%d/%q", *merged.UsageType, lo.FromPtr(merged.RiskScore), merged.ScoreFloor,
+					tt.wantType, tt.wantScore, tt.wantFloor)
+			}
+		})
+	}
+}
+
+func Test_parseRules_Invalid(t *testing.T) {
+	tests := []string{
+		`- {name: a, asn: [1]}`,
+		`- {name: a, usage_type: Residential}`,
+		`- {name: a, cidr: ["1.2.3.4/40"], usage_type: Residential}`,
+		`- {name: a, org: "(", usage_type: Residential}`,
+		`- {name: a, asn: [1], usage_type: unknown}`,
+		`- {name: a, asn: [1], risk_score: 101}`,
+	}
+	for _, tt := range tests {
+		if _, err := parseRules([]byte(tt)); err == nil {
+			t.Errorf("parseRules(%q) expected error", tt)
+		}
+	}
+}
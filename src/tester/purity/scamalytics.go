@@ -0,0 +1,201 @@
+package purity
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/samber/lo"
+	"resty.dev/v3"
+)
+
+// https://scamalytics.com/ip/api/enquiry
+// scamalytics_score 0-100, 越高越风险; 接口域名随账户分配, 密钥格式为 "用户名:密钥"
+const ScamalyticsAPI = "https://%s/v3/%s/"
+
+type ScamalyticsResponse struct {
+	Scamalytics struct {
+		Status           string `json:"status"`
+		Error            string `json:"error"`
+		IP               string `json:"ip"`
+		Score            int    `json:"scamalytics_score"`
+		Risk             string `json:"scamalytics_risk"`
+		ISP              string `json:"scamalytics_isp"`
+		Org              string `json:"scamalytics_org"`
+		IsBlacklisted    bool   `json:"is_blacklisted_external"`
+		ScamalyticsProxy struct {
+			IsDatacenter       bool `json:"is_datacenter"`
+			IsVPN              bool `json:"is_vpn"`
+			IsAppleICloudRelay bool `json:"is_apple_icloud_private_relay"`
+			IsAmazonAWS        bool `json:"is_amazon_aws"`
+			IsGoogle           bool `json:"is_google"`
+		} `json:"scamalytics_proxy"`
+	} `json:"scamalytics"`
+	ExternalDatasources struct {
+		DBIP struct {
+			IPCountryCode  string `json:"ip_country_code"`
+			IPStateName    string `json:"ip_state_name"`
+			IPCity         string `json:"ip_city"`
+			ASN            string `json:"asn"`
+			ISPName        string `json:"isp_name"`
+			ConnectionType string `json:"connection_type"`
+		} `json:"dbip"`
+		X4BNet struct {
+			IsTor          bool `json:"is_tor"`
+			IsVPN          bool `json:"is_vpn"`
+			IsDatacenter   bool `json:"is_datacenter"`
+			IsBlacklisted  bool `json:"is_blacklisted_spambot"`
+			IsBotOperamini bool `json:"is_bot_operamini"`
+			IsBotSemrush   bool `json:"is_bot_semrush"`
+		} `json:"x4bnet"`
+		Firehol struct {
+			IsProxy bool `json:"is_proxy"`
+		} `json:"firehol"`
+	} `json:"external_datasources"`
+}
+
+// {
+//   "scamalytics": {
+//     "status": "ok",
+//     "mode": "live",
+//     "ip": "203.0.113.88",
+//     "scamalytics_score": 57,
+//     "scamalytics_risk": "medium",
+//     "scamalytics_isp": "HK Example Tech Co.",
+//     "scamalytics_org": "HK Example Tech Co.",
+//     "is_blacklisted_external": false,
+//     "scamalytics_proxy": {
+//       "is_datacenter": true,
+//       "is_vpn": false,
+//       "is_apple_icloud_private_relay": false,
+//       "is_amazon_aws": false,
+//       "is_google": false
+//     }
+//   },
+//   "external_datasources": {
+//     "dbip": {
+//       "ip_country_code": "HK",
+//       "ip_state_name": "Hong Kong",
+//       "ip_city": "Hong Kong",
+//       "asn": "133750",
+//       "isp_name": "HK Example Tech Co.",
+//       "connection_type": "corporate"
+//     },
+//     "x4bnet": {
+//       "is_tor": false,
+//       "is_vpn": false,
+//       "is_datacenter": true,
+//       "is_blacklisted_spambot": false,
+//       "is_bot_operamini": false,
+//       "is_bot_semrush": false
+//     },
+//     "firehol": {
+//       "is_proxy": false
+//     }
+//   }
+// }
+
+type ScamalyticsDetector struct {
+	APIKey *ApiKey
+	Host   string
+}
+
+func NewScamalyticsDetector(apiKey *ApiKey, host string) *ScamalyticsDetector {
+	return &ScamalyticsDetector{
+		APIKey: apiKey,
+		Host:   host,
+	}
+}
+
+func (d *ScamalyticsDetector) Name() string {
+	return "Scamalytics"
+}
+
+func (d *ScamalyticsDetector) Detect(client *resty.Client, ip string) (*proxiePurity, error) {
+	if client == nil {
+		return nil, fmt.Errorf("HTTP客户端不能为空")
+	}
+
+	if d.APIKey == nil {
+		return nil, fmt.Errorf("未提供Scamalytics API密钥")
+	}
+
+	var scamResp ScamalyticsResponse
+	key, resp, err := d.APIKey.Request(func(key string) (*resty.Response, error) {
+		scamResp = ScamalyticsResponse{}
+		user, secret, ok := strings.Cut(key, ":")
+		if !ok {
+			return nil, fmt.Errorf("Scamalytics API密钥格式错误, 应为 用户名:密钥")
+		}
+		url := fmt.Sprintf(ScamalyticsAPI, d.Host, user)
+		return client.R().
+			SetQueryParam("key", secret).
+			SetQueryParam("ip", ip).
+			SetResult(&scamResp).
+			Get(url)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("请求Scamalytics API失败: %w", err)
+	}
+
+	if resp.StatusCode() != 200 {
+		return nil, fmt.Errorf("Scamalytics API返回非200状态码: %d", resp.StatusCode())
+	}
+
+	scam := scamResp.Scamalytics
+	if scam.Status != "ok" {
+		if msg := strings.ToLower(scam.Error); strings.Contains(msg, "quota") || strings.Contains(msg, "limit") {
+			d.APIKey.Exhausted(key, scam.Error)
+		}
+		return nil, fmt.Errorf("Scamalytics API返回错误: %s", scam.Error)
+	}
+
+	dbip := scamResp.ExternalDatasources.DBIP
+	x4b := scamResp.ExternalDatasources.X4BNet
+
+	result := &proxiePurity{
+		IP:         lo.ToPtr(ip),
+		Country:    lo.EmptyableToPtr(dbip.IPCountryCode),
+		Region:     lo.EmptyableToPtr(dbip.IPStateName),
+		City:       lo.EmptyableToPtr(dbip.IPCity),
+		RiskScore:  lo.ToPtr(scam.Score),
+		Org:        lo.EmptyableToPtr(lo.CoalesceOrEmpty(scam.ISP, dbip.ISPName)),
+		DetectName: lo.ToPtr(d.Name()),
+	}
+	if asn, err := strconv.Atoi(dbip.ASN); err == nil {
+		result.ASN = lo.ToPtr(asn)
+	}
+
+	isDatacenter := scam.ScamalyticsProxy.IsDatacenter || scam.ScamalyticsProxy.IsAmazonAWS ||
+		scam.ScamalyticsProxy.IsGoogle || x4b.IsDatacenter
+
+	result.RiskFactors = RiskFactors{
+		IsProxy:  lo.ToPtr(scamResp.ExternalDatasources.Firehol.IsProxy || scam.ScamalyticsProxy.IsAppleICloudRelay),
+		IsVPN:    lo.ToPtr(scam.ScamalyticsProxy.IsVPN || x4b.IsVPN),
+		IsTor:    lo.ToPtr(x4b.IsTor),
+		IsServer: lo.ToPtr(isDatacenter),
+		IsAbuse:  lo.ToPtr(scam.IsBlacklisted || x4b.IsBlacklisted),
+		IsBot:    lo.ToPtr(x4b.IsBotOperamini || x4b.IsBotSemrush),
+	}
+
+	var usageType UsageType
+
+	switch {
+	case isDatacenter:
+		usageType = UsageTypeDatacenter
+	default:
+		switch strings.ToLower(dbip.ConnectionType) {
+		case "residential", "cable/dsl", "dialup":
+			usageType = UsageTypeResidential
+		case "corporate", "hosting":
+			usageType = UsageTypeDatacenter
+		default:
+			usageType = UsageTypeOther
+		}
+	}
+	result.UsageType = lo.ToPtr(usageType)
+
+	return result, nil
+}
+
+var _ IPDetector = &ScamalyticsDetector{}
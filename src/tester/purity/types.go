@@ -1,6 +1,11 @@
 package purity
 
 import (
+	"cmp"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/samber/lo"
@@ -51,6 +56,10 @@ type proxiePurity struct {
 
 	Region *string // 地区/省份
 	City   *string // 城市
+	ASN    *int    // 自治系统号
+	Org    *string // ASN所属组织/运营商
+
+	Hostname *string // PTR记录主机名
 
 	DetectName *string
 	Error      *string // 错误信息（可选）
@@ -63,7 +72,17 @@ type PurityResult struct {
 	TypeIcon    string // IP使用类型图标
 
 	LastUpdated time.Time // 最后更新时间
-	Results     map[string]*proxiePurity
+	Stale       bool      // 已超过有效期，仍在保留窗口内
+	VerdictAt   time.Time // 引用的出口IP检测结果的检测时间
+	Cached      bool      // 是否复用了出口IP的缓存结果
+	ScoringHash string    // 合并时使用的评分配置摘要，与当前conf不一致时重新合并
+
+	Contributions []Contribution    // 各检测器对风险评分的贡献
+	ScoreFloor    string            // 触发的最低评分条件，如 datacenter，为空表示未修正
+	Detectors     []DetectorSummary // 参与合并的各检测器精简结果
+	Confidence    Confidence        // 检测器之间的一致程度
+	Rule          *RuleMatch        // 命中的自定义分类规则
+	Results       map[string]*proxiePurity
 }
 
 type IPDetector interface {
@@ -71,6 +90,42 @@ type IPDetector interface {
 	Detect(client *resty.Client, ip string) (*proxiePurity, error)
 }
 
+// Contribution 单个检测器对合并风险评分的贡献
+type Contribution struct {
+	Detector string
+	Weight   float64 // 信任权重
+	Score    int     // 检测器评分
+	Derived  bool    // 检测器未返回评分，由使用类型及风险因子推算
+	Points   float64 // 计入加权平均分的分值，各检测器之和为最低评分修正前的风险评分
+}
+
+// detectorScore 返回检测器的风险评分，未返回评分时按使用类型及风险因子推算
+func detectorScore(profile *models.ScoringProfile, r *proxiePurity) (score int, derived bool, ok bool) {
+	if r.RiskScore != nil {
+		return *r.RiskScore, false, true
+	}
+	if r.RiskFactors.AllNil() {
+		return 0, false, false
+	}
+	isTrue := func(b *bool) bool { return b != nil && *b }
+	f := profile.Factors
+	if r.UsageType != nil {
+		switch *r.UsageType {
+		case UsageTypeDatacenter:
+			score += f.Datacenter
+		case UsageTypeOther:
+			score += f.Other
+		}
+	}
+	score += lo.Ternary(isTrue(r.RiskFactors.IsProxy), f.Proxy, 0)
+	score += lo.Ternary(isTrue(r.RiskFactors.IsVPN), f.VPN, 0)
+	score += lo.Ternary(isTrue(r.RiskFactors.IsTor), f.Tor, 0)
+	score += lo.Ternary(isTrue(r.RiskFactors.IsServer), f.Server, 0)
+	score += lo.Ternary(isTrue(r.RiskFactors.IsAbuse), f.Abuse, 0)
+	score += lo.Ternary(isTrue(r.RiskFactors.IsBot), f.Bot, 0)
+	return min(100, score), true, true
+}
+
 func MergeIPInfo(conf *models.Conf, results []*proxiePurity) *PurityResult {
 	if len(results) == 0 {
 		return &PurityResult{
@@ -80,9 +135,14 @@ func MergeIPInfo(conf *models.Conf, results []*proxiePurity) *PurityResult {
 		}
 	}
 
-	// 过滤掉错误结果
+	profile := &conf.Scoring
+	weightOf := func(r *proxiePurity) float64 {
+		return profile.DetectorWeight(lo.FromPtr(r.DetectName))
+	}
+
+	// 过滤掉错误结果及权重为0的检测器
 	validResults := lo.Filter(results, func(r *proxiePurity, _ int) bool {
-		return r.Error == nil || *r.Error == ""
+		return (r.Error == nil || *r.Error == "") && weightOf(r) > 0
 	})
 
 	if len(validResults) == 0 {
@@ -93,75 +153,73 @@ func MergeIPInfo(conf *models.Conf, results []*proxiePurity) *PurityResult {
 		}
 	}
 
+	// 权重高的检测器优先提供地区等信息
+	slices.SortStableFunc(validResults, func(a, b *proxiePurity) int {
+		return cmp.Compare(weightOf(b), weightOf(a))
+	})
+
 	// 初始化合并结果
 	merged := PurityResult{
 		LastUpdated: time.Now(),
 	}
 
-	// 初始化风险因子计数器
+	// 初始化风险因子计数器，按检测器权重累加
 	riskFactorCounts := struct {
-		IsProxy  int
-		IsTor    int
-		IsVPN    int
-		IsServer int
-		IsAbuse  int
-		IsBot    int
+		IsProxy  float64
+		IsTor    float64
+		IsVPN    float64
+		IsServer float64
+		IsAbuse  float64
+		IsBot    float64
 	}{}
 
-	// 统计各类型的使用次数
-	usageTypeCounts := make(map[UsageType]int)
+	// 统计各类型的使用权重
+	usageTypeCounts := make(map[UsageType]float64)
+	usageTypeOrder := make([]UsageType, 0)
 
 	merged.Country = findFirstNonNil(validResults, func(r *proxiePurity) *string { return r.Country })
 	merged.Region = findFirstNonNil(validResults, func(r *proxiePurity) *string { return r.Region })
 	merged.City = findFirstNonNil(validResults, func(r *proxiePurity) *string { return r.City })
+	merged.ASN = findFirstNonNil(validResults, func(r *proxiePurity) *int { return r.ASN })
+	merged.Org = findFirstNonNil(validResults, func(r *proxiePurity) *string { return r.Org })
+	merged.Hostname = findFirstNonNil(validResults, func(r *proxiePurity) *string { return r.Hostname })
 
 	// merged.CompanyType = findFirstNonNil(validResults, func(r *IPInfo) *string { return r.CompanyType })
 
 	merged.IP = findFirstNonNil(validResults, func(r *proxiePurity) *string { return r.IP })
 
 	// 处理风险评分和风险因子
-	totalRiskScore := 0
-	countRiskScore := 0
+	totalWeight := 0.0
+	scoreWeight := 0.0
+	weightedScore := 0.0
 
 	// 遍历所有有效结果
 	for _, result := range validResults {
+		weight := weightOf(result)
+		totalWeight += weight
+
 		// 累加风险评分, 如果有风险因子则也进行统计
-		if result.RiskScore != nil {
-			countRiskScore++
-			totalRiskScore += *result.RiskScore
-		} else if !result.RiskFactors.AllNil() {
-			countRiskScore++
-			score := 0
-			if result.UsageType != nil {
-				switch *result.UsageType {
-				case UsageTypeDatacenter:
-					score += 30
-				case UsageTypeOther:
-					score += 20
-				}
-			}
-			if result.RiskFactors.IsProxy != nil && *result.RiskFactors.IsProxy {
-				score += 30
-			}
-			if result.RiskFactors.IsVPN != nil && *result.RiskFactors.IsVPN {
-				score += 25
-			}
-			if result.RiskFactors.IsTor != nil && *result.RiskFactors.IsTor {
-				score += 40
-			}
-			if result.RiskFactors.IsServer != nil && *result.RiskFactors.IsServer {
-				score += 15
-			}
-			totalRiskScore += min(100, score)
+		if score, derived, ok := detectorScore(profile, result); ok {
+			scoreWeight += weight
+			weightedScore += weight * float64(score)
+			merged.Contributions = append(merged.Contributions, Contribution{
+				Detector: lo.FromPtr(result.DetectName),
+				Weight:   weight,
+				Score:    score,
+				Derived:  derived,
+			})
 		}
 
 		// 统计使用类型
 		if result.UsageType != nil {
-			usageTypeCounts[*result.UsageType]++
+			if _, ok := usageTypeCounts[*result.UsageType]; !ok {
+				usageTypeOrder = append(usageTypeOrder, *result.UsageType)
+			}
+			usageTypeCounts[*result.UsageType] += weight
 		}
 
-		countIfTrue := func(b *bool) int {
-			return lo.Ternary(b != nil && *b, 1, 0)
+		countIfTrue := func(b *bool) float64 {
+			return lo.Ternary(b != nil && *b, weight, 0)
 		}
 
 		riskFactorCounts.IsProxy += countIfTrue(result.RiskFactors.IsProxy)
@@ -173,15 +231,12 @@ func MergeIPInfo(conf *models.Conf, results []*proxiePurity) *PurityResult {
 
 	}
 
-	// 设置风险评分
-	riskScore := totalRiskScore / countRiskScore
-
 	// 设置风险因子（根据权重）
-	threshold := float64(len(validResults)) * 0.3 // 30%权重阈值
+	threshold := totalWeight * profile.Threshold
 
 	// 创建风险因子评估函数
-	exceedsThreshold := func(count int) bool {
-		return float64(count) >= threshold
+	exceedsThreshold := func(count float64) bool {
+		return count > 0 && count >= threshold
 	}
 
 	merged.RiskFactors = RiskFactors{
@@ -193,31 +248,28 @@ func MergeIPInfo(conf *models.Conf, results []*proxiePurity) *PurityResult {
 		IsBot:    lo.ToPtr(exceedsThreshold(riskFactorCounts.IsBot)),
 	}
 
-	if len(usageTypeCounts) > 0 {
-		mostCommonType, _ := lo.FindKeyBy(usageTypeCounts, func(_ UsageType, count int) bool {
-			return count == lo.Max(lo.Values(usageTypeCounts))
+	if len(usageTypeOrder) > 0 {
+		mostCommonType := lo.MaxBy(usageTypeOrder, func(a, b UsageType) bool {
+			return usageTypeCounts[a] > usageTypeCounts[b]
 		})
 		merged.UsageType = lo.ToPtr(mostCommonType)
 	} else {
 		merged.UsageType = lo.ToPtr(UsageTypeOther)
 	}
 
-	{
-		switch *merged.UsageType {
-		case UsageTypeDatacenter:
-			riskScore = max(riskScore, 50)
-		case UsageTypeOther:
-			riskScore = max(riskScore, 30)
-		}
-		if merged.RiskFactors.IsProxy != nil && *merged.RiskFactors.IsProxy {
-			riskScore = max(riskScore, 20)
-		}
-		if merged.RiskFactors.IsVPN != nil && *merged.RiskFactors.IsVPN {
-			riskScore = max(riskScore, 10)
+	// 没有检测器给出评分或风险因子时，风险评分未知
+	if scoreWeight > 0 {
+		for i := range merged.Contributions {
+			c := &merged.Contributions[i]
+			c.Points = c.Weight * float64(c.Score) / scoreWeight
 		}
+		applyFloors(profile, &merged, int(math.Round(weightedScore/scoreWeight)))
 	}
 
-	merged.RiskScore = lo.ToPtr(riskScore)
+	merged.Detectors = lo.Map(validResults, func(r *proxiePurity, _ int) DetectorSummary {
+		return summarize(r)
+	})
+	merged.Confidence = computeConfidence(profile, validResults, &merged)
 
 	if env.Conf.Debug {
 		allResults := make(map[string]*proxiePurity)
@@ -230,12 +282,36 @@ func MergeIPInfo(conf *models.Conf, results []*proxiePurity) *PurityResult {
 	}
 
 	merged.CountryFlag = GetCountryFlag(merged.Country)
-	merged.PurityIcon = GetPurityIcon(conf, merged.RiskScore)
+	merged.PurityIcon = GetPurityIcon(conf, merged.RiskScore, &merged.Confidence)
 	merged.TypeIcon = GetTypeIcon(conf, merged.UsageType)
 
 	return &merged
 }
 
+// applyFloors 按使用类型及风险因子修正最低评分，score为修正前的加权评分
+func applyFloors(profile *models.ScoringProfile, merged *PurityResult, score int) {
+	merged.ScoreFloor = ""
+	floor := func(name string, value int) {
+		if value > score {
+			score = value
+			merged.ScoreFloor = name
+		}
+	}
+	switch lo.FromPtr(merged.UsageType) {
+	case UsageTypeDatacenter:
+		floor("datacenter", profile.Floors.Datacenter)
+	case UsageTypeOther:
+		floor("other", profile.Floors.Other)
+	}
+	if merged.RiskFactors.IsProxy != nil && *merged.RiskFactors.IsProxy {
+		floor("proxy", profile.Floors.Proxy)
+	}
+	if merged.RiskFactors.IsVPN != nil && *merged.RiskFactors.IsVPN {
+		floor("vpn", profile.Floors.VPN)
+	}
+	merged.RiskScore = lo.ToPtr(score)
+}
+
 func CreateEmptyIPInfo(ip string) *proxiePurity {
 	return &proxiePurity{
 		IP:        nil,
@@ -272,7 +348,11 @@ func GetCountryFlag(_code *string) string {
 // <60  → 3: 💛 一般
 // <80  → 4: 🧡 较脏
 // >=80 → 5: ❤️ 污染严重
-func GetPurityIcon(i *models.Conf, riskScore *int) string {
+// 配置 uncertain_icon 且检测器分歧较大时返回该图标
+func GetPurityIcon(i *models.Conf, riskScore *int, confidence *Confidence) string {
+	if i.UncertainIcon != "" && confidence.Uncertain(&i.Scoring) {
+		return i.UncertainIcon
+	}
 	if riskScore == nil {
 		return i.PurityIcon[0]
 	}
@@ -299,6 +379,20 @@ func GetTypeIcon(i *models.Conf, usageType *UsageType) string {
 	}
 }
 
+// parseASN 解析 "AS4134 CHINANET" 或 "AS4134" 格式的ASN及组织名
+func parseASN(str string) (*int, *string) {
+	str = strings.TrimSpace(str)
+	if !strings.HasPrefix(strings.ToUpper(str), "AS") {
+		return nil, lo.EmptyableToPtr(str)
+	}
+	numStr, org, _ := strings.Cut(str[2:], " ")
+	asn, err := strconv.Atoi(numStr)
+	if err != nil {
+		return nil, lo.EmptyableToPtr(str)
+	}
+	return lo.ToPtr(asn), lo.EmptyableToPtr(strings.TrimSpace(org))
+}
+
 // findFirstNonNil 返回切片中第一个非nil的值
 func findFirstNonNil[T any, R any](items []T, getter func(T) *R) *R {
 	for _, item := range items {
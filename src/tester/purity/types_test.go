@@ -0,0 +1,119 @@
+package purity
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ocyss/sub-store-lab/src/models"
+	"github.com/samber/lo"
+)
+
+func TestMergeIPInfo_Scoring(t *testing.T) {
+	results := []*proxiePurity{
+		{DetectName: lo.ToPtr("A"), RiskScore: lo.ToPtr(80), UsageType: lo.ToPtr(UsageTypeResidential), Country: lo.ToPtr("US")},
+		{DetectName: lo.ToPtr("B"), RiskScore: lo.ToPtr(20), UsageType: lo.ToPtr(UsageTypeResidential), Country: lo.ToPtr("HK")},
+		{DetectName: lo.ToPtr("C"), UsageType: lo.ToPtr(UsageTypeResidential), RiskFactors: RiskFactors{IsVPN: lo.ToPtr(true)}},
+	}
+
+	tests := []struct {
+		name        string
+		scoring     func(p *models.ScoringProfile)
+		wantScore   int
+		wantFloor   string
+		wantCountry string
+		wantVPN     bool
+	}{
+		{
+			name:        "default",
+			wantScore:   42, // (80+20+25)/3
+			wantCountry: "US",
+			wantVPN:     true,
+		},
+		{
+			name: "detector weight",
+			scoring: func(p *models.ScoringProfile) {
+				p.Detectors = map[string]float64{"b": 3, "C": 0}
+			},
+			wantScore:   35, // (80+20*3)/4
+			wantCountry: "HK",
+			wantVPN:     false,
+		},
+		{
+			name: "factor weight and floor",
+			scoring: func(p *models.ScoringProfile) {
+				p.Factors.VPN = 50
+				p.Floors.VPN = 60
+			},
+			wantScore:   60,
+			wantFloor:   "vpn",
+			wantCountry: "US",
+			wantVPN:     true,
+		},
+		{
+			name: "threshold",
+			scoring: func(p *models.ScoringProfile) {
+				p.Threshold = 0.5
+			},
+			wantScore:   42,
+			wantCountry: "US",
+			wantVPN:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := models.DefaultConf()
+			if tt.scoring != nil {
+				tt.scoring(&conf.Scoring)
+			}
+			got := MergeIPInfo(conf, results)
+			if got.RiskScore == nil || *got.RiskScore != tt.wantScore {
+				t.Errorf("RiskScore = %v, want %d", got.RiskScore, tt.wantScore)
+			}
+			if got.ScoreFloor != tt.wantFloor {
+				t.Errorf("ScoreFloor = %q, want %q", got.ScoreFloor, tt.wantFloor)
+			}
+			if lo.FromPtr(got.Country) != tt.wantCountry {
+				t.Errorf("Country = %v, want %s", lo.FromPtr(got.Country), tt.wantCountry)
+			}
+			if lo.FromPtr(got.RiskFactors.IsVPN) != tt.wantVPN {
+				t.Errorf("IsVPN = %v, want %v", lo.FromPtr(got.RiskFactors.IsVPN), tt.wantVPN)
+			}
+			points := 0.0
+			for _, c := range got.Contributions {
+				points += c.Points
+			}
+			if tt.wantFloor == "" && math.Round(points) != float64(tt.wantScore) {
+				t.Errorf("sum of contribution points = %v, want %d", points, tt.wantScore)
+			}
+		})
+	}
+}
+
+func TestMergeIPInfo_Confidence(t *testing.T) {
+	results := []*proxiePurity{
+		{DetectName: lo.ToPtr("A"), RiskScore: lo.ToPtr(80), UsageType: lo.ToPtr(UsageTypeDatacenter), Country: lo.ToPtr("US")},
+		{DetectName: lo.ToPtr("B"), RiskScore: lo.ToPtr(20), UsageType: lo.ToPtr(UsageTypeDatacenter), Country: lo.ToPtr("hk")},
+		{DetectName: lo.ToPtr("C"), UsageType: lo.ToPtr(UsageTypeResidential), Country: lo.ToPtr("US")},
+		{DetectName: lo.ToPtr("D"), Country: lo.ToPtr("US")},
+	}
+	conf := models.DefaultConf()
+	conf.UncertainIcon = "❔"
+
+	got := MergeIPInfo(conf, results)
+	want := Confidence{CountryAgreement: 0.75, UsageShare: 2.0 / 3, ScoreSpread: 60}
+	if got.Confidence != want {
+		t.Errorf("Confidence = %+v, want %+v", got.Confidence, want)
+	}
+	if len(got.Detectors) != len(results) {
+		t.Errorf("len(Detectors) = %d, want %d", len(got.Detectors), len(results))
+	}
+	if got.PurityIcon != "❔" {
+		t.Errorf("PurityIcon = %s, want uncertain icon", got.PurityIcon)
+	}
+
+	conf.Scoring.MaxSpread = 0
+	if icon := GetPurityIcon(conf, got.RiskScore, &got.Confidence); icon == "❔" {
+		t.Errorf("GetPurityIcon() = %s, want certain icon when spread is ignored", icon)
+	}
+}
@@ -0,0 +1,164 @@
+package tester
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+	"github.com/ocyss/sub-store-lab/src/env"
+	"github.com/ocyss/sub-store-lab/src/models"
+)
+
+// quietWindow 一天内的静默时段，以距离零点的分钟数表示，start > end 表示跨零点
+type quietWindow struct {
+	start int
+	end   int
+}
+
+// confLocation 返回conf使用的时区，conf未配置时使用全局配置，均未配置返回nil
+func confLocation(conf *models.Conf) *time.Location {
+	name := conf.Timezone
+	if name == "" {
+		name = env.Conf.Timezone
+	}
+	if name == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		slog.Warn("invalid timezone, fallback to local", "id", conf.Id, "timezone", name, "error", err)
+		return nil
+	}
+	return loc
+}
+
+// cronExprOf 返回测试器在conf下带时区的cron表达式
+func cronExprOf(conf *models.Conf, t models.ProxieTester) string {
+	expr := t.Cron(conf)
+	if loc := confLocation(conf); loc != nil && !strings.HasPrefix(expr, "TZ=") && !strings.HasPrefix(expr, "CRON_TZ=") {
+		return fmt.Sprintf("CRON_TZ=%s %s", loc.String(), expr)
+	}
+	return expr
+}
+
+func parseQuietHours(str string) ([]quietWindow, error) {
+	var windows []quietWindow
+	for part := range strings.SplitSeq(str, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		startStr, endStr, ok := strings.Cut(part, "-")
+		if !ok {
+			return nil, fmt.Errorf("静默时段格式错误: %s", part)
+		}
+		start, err := parseClock(startStr)
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseClock(endStr)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, quietWindow{start: start, end: end})
+	}
+	return windows, nil
+}
+
+func parseClock(str string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(str))
+	if err != nil {
+		return 0, fmt.Errorf("时间格式错误: %s", str)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// contains 返回now是否处于静默时段，以及静默结束的时间
+func (w quietWindow) contains(now time.Time) (time.Time, bool) {
+	minute := now.Hour()*60 + now.Minute()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch {
+	case w.start == w.end:
+		return time.Time{}, false
+	case w.start < w.end:
+		if minute >= w.start && minute < w.end {
+			return midnight.Add(time.Duration(w.end) * time.Minute), true
+		}
+	case minute >= w.start:
+		return midnight.AddDate(0, 0, 1).Add(time.Duration(w.end) * time.Minute), true
+	case minute < w.end:
+		return midnight.Add(time.Duration(w.end) * time.Minute), true
+	}
+	return time.Time{}, false
+}
+
+// quietUntil 当前处于conf静默时段时返回静默结束时间
+func quietUntil(conf *models.Conf, now time.Time) (time.Time, bool) {
+	if conf.QuietHours == "" {
+		return time.Time{}, false
+	}
+	windows, err := parseQuietHours(conf.QuietHours)
+	if err != nil {
+		slog.Warn("invalid quiet hours", "id", conf.Id, "quiet_hours", conf.QuietHours, "error", err)
+		return time.Time{}, false
+	}
+	if loc := confLocation(conf); loc != nil {
+		now = now.In(loc)
+	}
+	for _, w := range windows {
+		if until, ok := w.contains(now); ok {
+			return until, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// startJitter 返回随机的启动延迟
+func startJitter(conf *models.Conf) time.Duration {
+	if conf.Jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.IntN(conf.Jitter+1)) * time.Second
+}
+
+// scheduledTask 定时任务入口
+func scheduledTask(task *CronTask) {
+	runTask(task, true)
+}
+
+// runTask 重任务在静默时段内推迟到时段结束后执行，jitter 为真时随机延后启动，仅用于定时触发
+func runTask(task *CronTask, jitter bool) {
+	delay := func() time.Duration {
+		if !jitter {
+			return 0
+		}
+		return startJitter(&task.Conf)
+	}
+	if t, ok := GetTester(task.Key.Type).(models.ProxieHeavyTester); ok && t.Heavy() {
+		if until, ok := quietUntil(&task.Conf, time.Now()); ok {
+			cronManager.deferTask(task, until.Add(delay()))
+			return
+		}
+	}
+	if d := delay(); d > 0 {
+		slog.Info("cron job start jitter", "key", task.Key, "delay", d)
+		time.Sleep(d)
+	}
+	taskFunc(task)
+}
+
+// deferTask 在指定时间单次执行任务
+func (m *CronManager) deferTask(task *CronTask, at time.Time) {
+	_, err := m.scheduler.NewJob(
+		gocron.OneTimeJob(gocron.OneTimeJobStartDateTime(at)),
+		gocron.NewTask(taskFunc, task),
+	)
+	if err != nil {
+		slog.Error("failed to defer cron job", "key", task.Key, "at", at, "error", err)
+		return
+	}
+	slog.Info("cron job deferred by quiet hours", "key", task.Key, "at", at)
+}
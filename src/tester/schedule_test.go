@@ -0,0 +1,116 @@
+package tester
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/go-co-op/gocron/v2"
+	"github.com/ocyss/sub-store-lab/src/env"
+	"github.com/ocyss/sub-store-lab/src/models"
+)
+
+func Test_quietUntil(t *testing.T) {
+	day := func(hour, minute int) time.Time {
+		return time.Date(2025, 10, 10, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name       string
+		quietHours string
+		now        time.Time
+		want       time.Time
+		wantOk     bool
+	}{
+		{"empty", "", day(20, 0), time.Time{}, false},
+		{"inside", "19:00-23:30", day(20, 0), day(23, 30), true},
+		{"before", "19:00-23:30", day(18, 59), time.Time{}, false},
+		{"end exclusive", "19:00-23:30", day(23, 30), time.Time{}, false},
+		{"cross midnight evening", "22:00-06:00", day(23, 0), day(6, 0).AddDate(0, 0, 1), true},
+		{"cross midnight morning", "22:00-06:00", day(5, 0), day(6, 0), true},
+		{"multi windows", "08:00-09:00, 19:00-23:00", day(8, 30), day(9, 0), true},
+		{"invalid", "19-23", day(20, 0), time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := &models.Conf{QuietHours: tt.quietHours, Timezone: "UTC"}
+			got, ok := quietUntil(conf, tt.now)
+			if ok != tt.wantOk || !got.Equal(tt.want) {
+				t.Errorf("quietUntil() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+// heavyTester 受静默时段限制的测试器
+type heavyTester struct {
+	countTester
+}
+
+func (h *heavyTester) Heavy() bool { return true }
+
+func TestRunTask_Manual(t *testing.T) {
+	if err := env.InitMemoryDB(); err != nil {
+		t.Fatalf("InitMemoryDB() error = %v", err)
+	}
+	defer env.CloseDB()
+	heavy := &heavyTester{countTester{runs: make(map[models.ProxieKey]int)}}
+	testers[heavy.Name()] = heavy
+	defer delete(testers, heavy.Name())
+
+	s, err := gocron.NewScheduler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Start()
+	defer func() { _ = s.Shutdown() }()
+	old := cronManager
+	cronManager = &CronManager{scheduler: s, jobs: make(map[models.CronJobKey]*CronJob), confs: make(map[string]*ConfState)}
+	defer func() { cronManager = old }()
+
+	conf := models.DefaultConf()
+	conf.Id = "conf"
+	conf.Timezone = "UTC"
+	conf.Jitter = 3600
+	key := models.ProxieKey{ConfId: conf.Id, SubName: "A", Fingerprint: "node"}
+	data, _ := json.Marshal(map[string]any{
+		"name": "node", "type": "ss", "server": "127.0.0.1", "port": 8388, "cipher": "aes-128-gcm", "password": "p",
+	})
+	if err := env.GetDB().Update(func(txn *badger.Txn) error { return txn.Set(key.ToKey(), data) }); err != nil {
+		t.Fatal(err)
+	}
+	task := func() *CronTask {
+		return &CronTask{Key: models.CronJobKey{ConfId: conf.Id, Type: heavy.Name()}, Conf: *conf}
+	}
+
+	// 手动运行不随机延后启动
+	done := make(chan struct{})
+	go func() {
+		(&CronJob{}).RunTask(task())
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunTask() delayed by jitter")
+	}
+	if heavy.runs[key] != 1 {
+		t.Fatalf("ran %d times, want 1", heavy.runs[key])
+	}
+
+	// 静默时段内推迟到时段结束，同样不随机延后
+	now := time.Now().UTC()
+	until := now.Add(time.Hour).Truncate(time.Minute)
+	conf.QuietHours = now.Add(-time.Hour).Format("15:04") + "-" + until.Format("15:04")
+	(&CronJob{}).RunTask(task())
+	if heavy.runs[key] != 1 {
+		t.Errorf("ran %d times during quiet hours, want deferred", heavy.runs[key])
+	}
+	jobs := s.Jobs()
+	if len(jobs) != 1 {
+		t.Fatalf("scheduler has %d jobs, want 1 deferred job", len(jobs))
+	}
+	if next, err := jobs[0].NextRun(); err != nil || !next.Equal(until) {
+		t.Errorf("deferred to %v, %v, want %v", next, err, until)
+	}
+}
@@ -22,6 +22,7 @@ type SpeedResult struct {
 	SpeedMbps int     // 下载速度(Mbps)
 
 	LastUpdated time.Time // 最后更新时间
+	Stale       bool      // 已超过有效期，仍在保留窗口内
 }
 
 type Speed struct{}
@@ -34,17 +35,26 @@ func (s *Speed) Cron(conf *models.Conf) string {
 	return conf.SpeedCron
 }
 
+// Heavy 测速占用大量带宽，静默时段内推迟执行
+func (s *Speed) Heavy() bool {
+	return true
+}
+
+// ResultDepends 测速结果依赖下载地址及下载限制
+func (s *Speed) ResultDepends(conf *models.Conf) any {
+	return []any{conf.SpeedTestUrl, conf.DownloadMB, conf.DownloadTimeout}
+}
+
 func (s *Speed) GetResult(proxy *models.ProxieInfo) (any, error) {
 	result, err := getResult[SpeedResult](s.Name(), proxy)
-	if err != nil {
+	if err != nil || result == nil {
 		return nil, err
 	}
-	if r, ok := result.(SpeedResult); ok {
-		if r.SpeedMbps < proxy.Conf.MinSpeed*8/1024 {
-			return nil, nil
-		}
+	if result.SpeedMbps < proxy.Conf.MinSpeed*8/1024 {
+		return nil, nil
 	}
-	return result, nil
+	result.Stale = isStale(result.LastUpdated, ResultTTL(proxy.Conf, s))
+	return *result, nil
 }
 
 func (s *Speed) RunTest(proxy *models.ProxieInfo, transport http.RoundTripper) (_ any, err error) {
@@ -77,7 +87,7 @@ func (s *Speed) RunTest(proxy *models.ProxieInfo, transport http.RoundTripper) (
 		return nil, fmt.Errorf("下载测试失败2: resp 或 resp.Body 为空")
 	}
 	if code := resp.StatusCode(); code != http.StatusOK {
-		return nil, fmt.Errorf("下载测试失败3: status %d", code)
+		return nil, fmt.Errorf("下载测试失败3: %w", &StatusError{StatusCode: code})
 	}
 	defer resp.Body.Close()
 
@@ -94,7 +104,7 @@ func (s *Speed) RunTest(proxy *models.ProxieInfo, transport http.RoundTripper) (
 
 	slog.Debug("速度测试完成",
 		"订阅", proxy.Id.SubName,
-		"节点", proxy.Id.ProxieName,
+		"节点", proxy.Name,
 		"总耗时", fmt.Sprintf("%.2f秒", elapsed),
 		"下载内容", utils.HumanBytes(int64(n)),
 		"下载速度", result.Speed,
@@ -102,4 +112,8 @@ func (s *Speed) RunTest(proxy *models.ProxieInfo, transport http.RoundTripper) (
 	return result, nil
 }
 
-var _ models.ProxieTester = &Speed{}
+var (
+	_ models.ProxieTester         = &Speed{}
+	_ models.ProxieResultDepender = &Speed{}
+	_ models.ProxieHeavyTester    = &Speed{}
+)
@@ -1,11 +1,13 @@
 package tester
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/dgraph-io/badger/v4"
 	"github.com/ocyss/sub-store-lab/src/env"
@@ -46,7 +48,7 @@ func GetTester(key models.ProxieTesterType) models.ProxieTester {
 	return testers[key]
 }
 
-func getResult[T any](name models.ProxieTesterType, proxy *models.ProxieInfo) (any, error) {
+func getResult[T any](name models.ProxieTesterType, proxy *models.ProxieInfo) (*T, error) {
 	if proxy == nil {
 		return nil, fmt.Errorf("tester[%s].GetResult: 无效的代理信息", name)
 	}
@@ -62,5 +64,35 @@ func getResult[T any](name models.ProxieTesterType, proxy *models.ProxieInfo) (a
 	} else if err != nil {
 		return nil, fmt.Errorf("tester[%s].GetResult id: %s: %w", name, proxy.Id, err)
 	}
-	return result, nil
+	return &result, nil
+}
+
+// updateResult 覆盖已保存的测试结果并保留原有效期，结果不存在时不写入
+func updateResult(name models.ProxieTesterType, proxy *models.ProxieInfo, value any) error {
+	resultKey := models.ProxieResultKey{
+		ProxieKey: proxy.Id,
+		Type:      name,
+	}
+	key := resultKey.ToKey()
+	return env.GetDB().Update(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		entry := badger.NewEntry(key, data)
+		if expiresAt := item.ExpiresAt(); expiresAt > 0 {
+			ttl := time.Until(time.Unix(int64(expiresAt), 0))
+			if ttl <= 0 {
+				return nil
+			}
+			entry = entry.WithTTL(ttl)
+		}
+		return txn.SetEntry(entry)
+	})
 }
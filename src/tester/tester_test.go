@@ -2,10 +2,18 @@ package tester
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/dgraph-io/badger/v4"
+	"github.com/ocyss/sub-store-lab/src/env"
 	"github.com/ocyss/sub-store-lab/src/models"
+	"github.com/ocyss/sub-store-lab/src/tester/purity"
 	"github.com/ocyss/sub-store-lab/src/utils"
 )
 
@@ -35,8 +43,116 @@ func TestSpeed_RunTest(t *testing.T) {
 	testTester(t, &Speed{})
 }
 
+// TestPurity_RunTest 回放 testdata/replay 中的出口IP及检测器响应，不访问网络，
+// 合并结果与 testdata/purity_result.json 比较，映射有意变更时使用 -update 更新期望结果
 func TestPurity_RunTest(t *testing.T) {
-	testTester(t, &Purity{})
+	old := env.Conf
+	t.Cleanup(func() { env.Conf = old })
+	replayDir, err := filepath.Abs(filepath.Join("testdata", "replay"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.Conf.DataDir = t.TempDir()
+	env.Conf.DetectorMode = purity.DetectorModeReplay
+	env.Conf.DetectorReplayDir = replayDir
+	env.Conf.IPCacheTTL = 0
+
+	var args models.Args
+	if err := json.Unmarshal([]byte(testProxies), &args); err != nil {
+		t.Fatalf("json.Unmarshal failed: %v", err)
+	}
+	// 回放模式不使用代理，请求未被录制时直接失败
+	offline := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, fmt.Errorf("offline: %s", req.URL)
+	})
+	got, err := (&Purity{}).RunTest(args.GetProxieInfo(args.Proxies[0]), offline)
+	if err != nil {
+		t.Fatalf("Purity.RunTest() error = %v", err)
+	}
+	result := got.(*purity.PurityResult)
+	// 时间字段每次运行不同，不参与比较
+	result.LastUpdated, result.VerdictAt = time.Time{}, time.Time{}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", "purity_result.json")
+	if *update {
+		if err := os.WriteFile(golden, append(data, '\n'), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("read golden file: %v", err)
+	}
+	if string(want) != string(data)+"\n" {
+		t.Errorf("Purity.RunTest() result differs from %s:\n%s", golden, data)
+	}
+}
+
+// TestPurity_GetResult_Remerge 评分配置变化后重新合并的结果写回数据库，并保留原有效期
+func TestPurity_GetResult_Remerge(t *testing.T) {
+	if err := env.InitMemoryDB(); err != nil {
+		t.Fatalf("InitMemoryDB() error = %v", err)
+	}
+	defer env.CloseDB()
+
+	conf := models.DefaultConf()
+	conf.Id = "conf"
+	proxy := &models.ProxieInfo{Id: models.ProxieKey{ConfId: conf.Id, SubName: "A", Fingerprint: "node"}, Conf: conf}
+	resultKey := models.ProxieResultKey{ProxieKey: proxy.Id, Type: (&Purity{}).Name()}
+	verdict := `{"IP":"1.2.3.4","Results":[` +
+		`{"DetectName":"A","IP":"1.2.3.4","RiskScore":80,"Country":"US"},` +
+		`{"DetectName":"B","IP":"1.2.3.4","RiskScore":20,"Country":"US"}]}`
+	stored := fmt.Sprintf(`{"IP":"1.2.3.4","RiskScore":50,"Country":"US","ScoringHash":%q}`, purity.ScoringHash(conf))
+	err := env.GetDB().Update(func(txn *badger.Txn) error {
+		if err := txn.Set((&models.IPPurityKey{IP: "1.2.3.4"}).ToKey(), []byte(verdict)); err != nil {
+			return err
+		}
+		return txn.SetEntry(badger.NewEntry(resultKey.ToKey(), []byte(stored)).WithTTL(time.Hour))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf.Scoring.Detectors = map[string]float64{"B": 3}
+	got, err := (&Purity{}).GetResult(proxy)
+	if err != nil {
+		t.Fatalf("Purity.GetResult() error = %v", err)
+	}
+	if result := got.(PurityResult); *result.RiskScore != 35 {
+		t.Errorf("RiskScore = %d, want 35", *result.RiskScore)
+	}
+
+	var saved PurityResult
+	var expiresAt uint64
+	err = env.GetDB().View(func(txn *badger.Txn) error {
+		item, err := txn.Get(resultKey.ToKey())
+		if err != nil {
+			return err
+		}
+		expiresAt = item.ExpiresAt()
+		return item.Value(func(val []byte) error { return json.Unmarshal(val, &saved) })
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.ScoringHash != purity.ScoringHash(conf) || *saved.RiskScore != 35 {
+		t.Errorf("saved = hash %s, risk %d, want remerged result", saved.ScoringHash, *saved.RiskScore)
+	}
+	if ttl := time.Until(time.Unix(int64(expiresAt), 0)); ttl <= 0 || ttl > time.Hour {
+		t.Errorf("ttl = %v, want original ttl kept", ttl)
+	}
+}
+
+var update = flag.Bool("update", false, "更新 testdata 中的期望结果")
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
 }
 
 func testTester(t *testing.T, p models.ProxieTester) {
@@ -0,0 +1,137 @@
+package tester
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ocyss/sub-store-lab/src/models"
+	"github.com/ocyss/sub-store-lab/src/utils"
+	"github.com/robfig/cron/v3"
+)
+
+// defaultTTL cron表达式无法解析时的默认结果有效期
+const defaultTTL = time.Hour * 48
+
+// ttlMargin 结果有效期在cron间隔之外的余量，覆盖一次运行的耗时
+const ttlMargin = time.Hour * 6
+
+// intervalCycle 计算cron间隔时覆盖的时长，包含闰年及各月天数差异
+const intervalCycle = time.Hour * 24 * 366
+
+// intervalMaxRuns 计算cron间隔时最多展开的执行次数，高频表达式的间隔在短时间内即可确定
+const intervalMaxRuns = 2000
+
+// intervalStart 从固定时间开始展开，避免间隔随计算时间变化
+var intervalStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var cronIntervals sync.Map // expr -> time.Duration
+
+// cronInterval 返回cron表达式在一个完整周期内相邻两次执行的最大间隔，
+// 如 0 2 */3 * * 在31日到次月1日只间隔1天，结果有效期需按3天计算
+func cronInterval(expr string) (time.Duration, bool) {
+	if v, ok := cronIntervals.Load(expr); ok {
+		return v.(time.Duration), true
+	}
+	schedule, err := cron.ParseStandard(expr)
+	if err != nil {
+		return 0, false
+	}
+	prev := schedule.Next(intervalStart)
+	if prev.IsZero() {
+		return 0, false
+	}
+	var interval time.Duration
+	end := prev.Add(intervalCycle)
+	for range intervalMaxRuns {
+		next := schedule.Next(prev)
+		if next.IsZero() {
+			break
+		}
+		interval = max(interval, next.Sub(prev))
+		if next.After(end) {
+			break
+		}
+		prev = next
+	}
+	if interval == 0 {
+		return 0, false
+	}
+	cronIntervals.Store(expr, interval)
+	return interval, true
+}
+
+// parsePositiveDuration 解析时长，不大于0时返回错误
+func parsePositiveDuration(s string) (time.Duration, error) {
+	d, err := utils.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("时长需大于0: %s", s)
+	}
+	return d, nil
+}
+
+// confDuration 读取conf中按测试器名称配置的时长，不区分大小写
+func confDuration(conf *models.Conf, values map[string]string, name models.ProxieTesterType) (time.Duration, bool) {
+	for k, v := range values {
+		if !strings.EqualFold(k, string(name)) {
+			continue
+		}
+		d, err := parsePositiveDuration(v)
+		if err != nil {
+			slog.Warn("invalid result ttl", "id", conf.Id, "tester", name, "value", v, "error", err)
+			return 0, false
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// ResultTTL 返回测试结果的有效期，未配置时由cron间隔推导
+func ResultTTL(conf *models.Conf, t models.ProxieTester) time.Duration {
+	if d, ok := confDuration(conf, conf.ResultTTL, t.Name()); ok {
+		return d
+	}
+	if interval, ok := cronInterval(cronExprOf(conf, t)); ok {
+		return interval + ttlMargin
+	}
+	return defaultTTL
+}
+
+// StaleTTL 返回结果过期后仍可使用（标记为stale）的时长，未配置时为一个cron间隔
+func StaleTTL(conf *models.Conf, t models.ProxieTester) time.Duration {
+	if conf.StaleTTL != "" {
+		d, err := parsePositiveDuration(conf.StaleTTL)
+		if err == nil {
+			return d
+		}
+		slog.Warn("invalid stale ttl", "id", conf.Id, "value", conf.StaleTTL, "error", err)
+	}
+	if interval, ok := cronInterval(cronExprOf(conf, t)); ok {
+		return interval
+	}
+	return 0
+}
+
+// StoreTTL 返回测试结果在数据库中的保留时长
+func StoreTTL(conf *models.Conf, t models.ProxieTester) time.Duration {
+	return ResultTTL(conf, t) + StaleTTL(conf, t)
+}
+
+// ProxieTTL 返回原始节点的保留时长，需覆盖所有测试器的结果保留时长
+func ProxieTTL(conf *models.Conf) time.Duration {
+	ttl := defaultTTL
+	for _, t := range GetTesters() {
+		ttl = max(ttl, StoreTTL(conf, t))
+	}
+	return ttl
+}
+
+// isStale 结果是否已超过有效期
+func isStale(lastUpdated time.Time, ttl time.Duration) bool {
+	return !lastUpdated.IsZero() && time.Since(lastUpdated) > ttl
+}
@@ -0,0 +1,62 @@
+package tester
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ocyss/sub-store-lab/src/models"
+)
+
+func Test_cronInterval(t *testing.T) {
+	tests := []struct {
+		expr string
+		want time.Duration
+		ok   bool
+	}{
+		{"0 3 * * *", 24 * time.Hour, true},
+		// 31日到次月1日只间隔1天，取最大间隔
+		{"0 2 */3 * *", 72 * time.Hour, true},
+		{"*/5 * * * *", 5 * time.Minute, true},
+		{"0 4 * * 1", 7 * 24 * time.Hour, true},
+		// 每月1日，最大间隔为31天
+		{"0 0 1 * *", 31 * 24 * time.Hour, true},
+		{"CRON_TZ=Asia/Shanghai 0 3 * * *", 24 * time.Hour, true},
+		{"invalid", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, ok := cronInterval(tt.expr)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("cronInterval(%q) = %v, %v, want %v, %v", tt.expr, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestResultTTL(t *testing.T) {
+	tester := &countTester{}
+	tests := []struct {
+		name      string
+		resultTTL map[string]string
+		staleTTL  string
+		want      time.Duration
+		wantStale time.Duration
+	}{
+		{"derived", nil, "", 24*time.Hour + ttlMargin, 24 * time.Hour},
+		{"configured", map[string]string{"count": "3d"}, "12h", 72 * time.Hour, 12 * time.Hour},
+		{"invalid", map[string]string{"Count": "abc"}, "abc", 24*time.Hour + ttlMargin, 24 * time.Hour},
+		{"zero", map[string]string{"Count": "0"}, "0s", 24*time.Hour + ttlMargin, 24 * time.Hour},
+		{"negative", map[string]string{"Count": "-1h"}, "-1h", 24*time.Hour + ttlMargin, 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := &models.Conf{ResultTTL: tt.resultTTL, StaleTTL: tt.staleTTL}
+			if got := ResultTTL(conf, tester); got != tt.want {
+				t.Errorf("ResultTTL() = %v, want %v", got, tt.want)
+			}
+			if got := StaleTTL(conf, tester); got != tt.wantStale {
+				t.Errorf("StaleTTL() = %v, want %v", got, tt.wantStale)
+			}
+		})
+	}
+}
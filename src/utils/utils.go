@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 func JsonToStr(v any) string {
@@ -72,3 +75,51 @@ func DeepCopy(src, dst any) error {
 		return err
 	}
 }
+
+// ParseDuration 在 time.ParseDuration 的基础上支持以天为单位，如 3d, 1d12h
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if days, rest, ok := strings.Cut(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		d := time.Duration(n) * 24 * time.Hour
+		if rest == "" {
+			return d, nil
+		}
+		r, err := time.ParseDuration(rest)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return d + r, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// ParseBytes 解析带单位的流量大小，如 10GB, 123.45 GB, 500M，单位按1024换算，无单位为字节
+func ParseBytes(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	num, unit := s, ""
+	if i >= 0 {
+		num, unit = s[:i], strings.TrimSpace(s[i:])
+	}
+	value, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	exp := strings.Index("BKMGTPE", strings.TrimSuffix(unit, "B"))
+	switch {
+	case unit == "" || unit == "B":
+		exp = 0
+	case exp < 0 || len(unit) > 2:
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	for range exp {
+		value *= 1024
+	}
+	return int64(value), nil
+}
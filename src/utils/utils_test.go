@@ -1,6 +1,9 @@
 package utils
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestHumanBytes(t *testing.T) {
 	tests := []struct {
@@ -31,3 +34,53 @@ func TestHumanBytes(t *testing.T) {
 		})
 	}
 }
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"48h", 48 * time.Hour, false},
+		{"3d", 72 * time.Hour, false},
+		{"1d12h", 36 * time.Hour, false},
+		{" 30m ", 30 * time.Minute, false},
+		{"xd", 0, true},
+		{"1dx", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := ParseDuration(tt.s)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ParseDuration(%q) = %v, %v, want %v, err %v", tt.s, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    int64
+		wantErr bool
+	}{
+		{"1024", 1024, false},
+		{"10GB", 10 << 30, false},
+		{"123.5 GB", int64(123.5 * (1 << 30)), false},
+		{"500m", 500 << 20, false},
+		{"1T", 1 << 40, false},
+		{"97GB", 97 << 30, false},
+		{"GB", 0, true},
+		{"10XB", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := ParseBytes(tt.s)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ParseBytes(%q) = %v, %v, want %v, err %v", tt.s, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}